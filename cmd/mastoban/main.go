@@ -1,14 +1,22 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/rmrfslashbin/mastoban/pkg/followup"
 	"github.com/rmrfslashbin/mastoban/pkg/geoip"
 	"github.com/rmrfslashbin/mastoban/pkg/mastoclient"
+	"github.com/rmrfslashbin/mastoban/pkg/policy"
+	"github.com/rmrfslashbin/mastoban/pkg/structs"
 	"github.com/rs/zerolog"
 )
 
@@ -23,10 +31,214 @@ type Context struct {
 	log *zerolog.Logger
 }
 
+// AccountCmd fetches the current state of an account
+type AccountCmd struct {
+	ID          string `required:"" name:"id" help:"ID of the account to fetch."`
+	Instance    string `required:"" name:"instance" help:"Instance the account is on."`
+	AccessToken string `required:"" name:"token" help:"Access token with the admin:read:accounts scope."`
+}
+
+// Run is the entry point for AccountCmd command
+func (r *AccountCmd) Run(ctx *Context) error {
+	// Create a new Mastoclient instance
+	mastodonClient, err := mastoclient.New(
+		mastoclient.WithInstance(r.Instance),       // Instance URL from CLI args
+		mastoclient.WithAccessToken(r.AccessToken), // Access Token from CLI args
+		mastoclient.WithLogger(ctx.log),
+	)
+	if err != nil {
+		return err
+	}
+
+	account, err := mastodonClient.GetAccount(r.ID)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(account, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+
+	return nil
+}
+
+// AccountsCmd lists the accounts on an instance, optionally
+// evaluating each one against a policy file
+type AccountsCmd struct {
+	Instance    string `required:"" name:"instance" help:"Instance to list accounts on."`
+	AccessToken string `required:"" name:"token" help:"Access token with the admin:read:accounts scope."`
+	Origin      string `name:"origin" enum:",local,remote" default:"" help:"Only list local or remote accounts."`
+	Status      string `name:"status" enum:",active,pending,disabled,silenced,suspended" default:"" help:"Only list accounts with this status."`
+	IP          string `name:"ip" help:"Only list accounts that used this IP address or CIDR range."`
+	Email       string `name:"email" help:"Only list accounts with this email address or domain."`
+	Username    string `name:"username" help:"Only list accounts with this username."`
+	Limit       int    `name:"limit" help:"Stop after this many accounts. Defaults to all of them."`
+	PolicyFile  string `name:"policy" env:"MASTOBAN_POLICY_FILE" help:"Path to a policy file to evaluate each account against. Nothing is enforced." type:"existingfile"`
+	DBFile      string `name:"dbfile" env:"DBFILE" help:"Path to the GeoIP country database file. Required with --policy."`
+	ASNDBFile   string `name:"asndbfile" env:"ASNDBFILE" help:"Path to the GeoIP ASN database file (optional)."`
+}
+
+// Run is the entry point for AccountsCmd command
+func (r *AccountsCmd) Run(ctx *Context) error {
+	// Create a new Mastoclient instance
+	mastodonClient, err := mastoclient.New(
+		mastoclient.WithInstance(r.Instance),       // Instance URL from CLI args
+		mastoclient.WithAccessToken(r.AccessToken), // Access Token from CLI args
+		mastoclient.WithLogger(ctx.log),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Set up the policy engine to scan the accounts with, if requested
+	var engine *policy.Engine
+	if r.PolicyFile != "" {
+		activePolicy, err := policy.Load(r.PolicyFile)
+		if err != nil {
+			return err
+		}
+
+		geoIPOpts := []geoip.Option{}
+		if r.ASNDBFile != "" {
+			geoIPOpts = append(geoIPOpts, geoip.WithASNDatabase(r.ASNDBFile))
+		}
+		geoIP, err := geoip.New(&r.DBFile, geoIPOpts...)
+		if err != nil {
+			return err
+		}
+
+		// Existing accounts are not new signups, so don't count them
+		engine, err = policy.New(
+			policy.WithGeoIP(geoIP),
+			policy.WithPolicy(activePolicy),
+			policy.WithVelocityReadOnly(),
+			policy.WithLogger(ctx.log),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	accounts := mastodonClient.ListAccounts(&mastoclient.AccountFilter{
+		Origin:   r.Origin,
+		Status:   r.Status,
+		IP:       r.IP,
+		Email:    r.Email,
+		Username: r.Username,
+	})
+	count := 0
+	for accounts.Next() {
+		account := accounts.Account()
+		fmt.Printf("%-20s  %-30s  %-9s  %-39s  %s",
+			account.Id, account.Username, accountStatus(account), account.Ip, account.CreatedAt.Format(time.RFC3339))
+
+		if engine != nil {
+			decision := engine.Evaluate(&structs.AccoutCreatedEvent{
+				Event:  "account.created",
+				Object: *account,
+			})
+			switch {
+			case decision.Err != nil:
+				fmt.Printf("  %s: %s", decision.Action, decision.Err)
+			case decision.Rule != "":
+				fmt.Printf("  %s: %s: %s", decision.Action, decision.Rule, decision.Reason)
+			default:
+				fmt.Printf("  %s", decision.Action)
+			}
+		}
+		fmt.Println()
+
+		count++
+		if r.Limit > 0 && count >= r.Limit {
+			break
+		}
+	}
+	return accounts.Err()
+}
+
+// accountStatus summarizes the moderation state of an account.
+func accountStatus(account *structs.AdminAccount) string {
+	switch {
+	case account.Suspended:
+		return "suspended"
+	case account.Disabled:
+		return "disabled"
+	case account.Silenced:
+		return "silenced"
+	case !account.Approved:
+		return "pending"
+	}
+	return "active"
+}
+
+// DivergenceCmd summarizes shadow policy divergences from worker logs
+type DivergenceCmd struct {
+	Files []string `arg:"" optional:"" name:"file" help:"Worker log files, one JSON event per line. Reads stdin when none are given." type:"existingfile"`
+}
+
+// Run is the entry point for DivergenceCmd command
+func (r *DivergenceCmd) Run(ctx *Context) error {
+	readers := []io.Reader{}
+	for _, file := range r.Files {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	if len(readers) == 0 {
+		readers = append(readers, os.Stdin)
+	}
+
+	summary, err := policy.SummarizeDivergences(io.MultiReader(readers...))
+	if err != nil {
+		return err
+	}
+
+	rate := 0.0
+	if summary.Decisions > 0 {
+		rate = float64(summary.Divergences) / float64(summary.Decisions) * 100
+	}
+	fmt.Printf("Decisions:   %d\n", summary.Decisions)
+	fmt.Printf("Divergences: %d (%.1f%%)\n", summary.Divergences, rate)
+
+	printCounts := func(title string, counts []policy.DivergenceCount) {
+		if len(counts) == 0 {
+			return
+		}
+		fmt.Println()
+		fmt.Println(title)
+		for _, count := range counts {
+			active, shadow := count.Active, count.Shadow
+			if active == "" {
+				active = "(none)"
+			}
+			if shadow == "" {
+				shadow = "(none)"
+			}
+			fmt.Printf("  %6d  %s -> %s\n", count.Count, active, shadow)
+		}
+	}
+	printCounts("By action (active -> shadow):", summary.Actions)
+	printCounts("By rule (active -> shadow):", summary.Rules)
+	fmt.Println()
+
+	return nil
+}
+
 // LookupCmd runs an IP address lookup through the GeoIP database
 type LookupCmd struct {
-	IP     string  `required:"" name:"ip" help:"IP address to parse."`
-	DBFile *string `name:"dbfile" env:"DBFILE" help:"Path to the GeoIP country database file."`
+	IP               string   `required:"" name:"ip" help:"IP address to parse."`
+	DBFile           *string  `name:"dbfile" env:"DBFILE" help:"Path to the GeoIP country database file."`
+	ASNDBFile        string   `name:"asndbfile" env:"ASNDBFILE" help:"Path to the GeoIP ASN database file (optional)."`
+	PolicyFile       *string  `name:"policy" env:"MASTOBAN_POLICY_FILE" help:"Path to a policy file to evaluate the IP address against."`
+	Permit           []string `name:"permit" env:"MASTOBAN_GEO_COUNTRY_PERMIT_LIST" help:"Comma separated list of country codes to permit."`
+	Deny             []string `name:"deny" env:"MASTOBAN_GEO_COUNTRY_DENY_LIST" help:"Comma separated list of country codes to deny. Takes precedence over --permit."`
+	PermitContinents []string `name:"permit-continents" env:"MASTOBAN_GEO_CONTINENT_PERMIT_LIST" help:"Comma separated list of continent codes to permit. Country lists override continent lists."`
+	DenyContinents   []string `name:"deny-continents" env:"MASTOBAN_GEO_CONTINENT_DENY_LIST" help:"Comma separated list of continent codes to deny. Country lists override continent lists."`
 }
 
 // Run is the entry point for LookupCmd command
@@ -37,8 +249,14 @@ func (r *LookupCmd) Run(ctx *Context) error {
 		return errors.New("invalid IP address")
 	}
 
+	// Use the GeoIP ASN database if provided
+	geoIPOpts := []geoip.Option{}
+	if r.ASNDBFile != "" {
+		geoIPOpts = append(geoIPOpts, geoip.WithASNDatabase(r.ASNDBFile))
+	}
+
 	// Create a new GeoIP DB instance
-	geoIP, err := geoip.New(r.DBFile)
+	geoIP, err := geoip.New(r.DBFile, geoIPOpts...)
 	if err != nil {
 		return err
 	}
@@ -49,15 +267,210 @@ func (r *LookupCmd) Run(ctx *Context) error {
 		return err
 	}
 
-	// Display a note if the IP is not in the US
-	if ipData.Country != "US" {
-		fmt.Println("*** IP address is not based in the US ***")
-	}
-
 	// Print the details from GeoIP DB lookup
 	fmt.Printf("IP Addr:   %s\n", ipData.IP)
 	fmt.Printf("Continent: %s\n", ipData.Continent)
 	fmt.Printf("Country:   %s\n", ipData.Country)
+	if ipData.ASN != 0 {
+		fmt.Printf("ASN:       AS%d (%s)\n", ipData.ASN, ipData.ASOrganization)
+	}
+
+	// Load the policy file, or build one from the geo lists
+	var activePolicy *policy.Policy
+	switch {
+	case r.PolicyFile != nil:
+		if activePolicy, err = policy.Load(*r.PolicyFile); err != nil {
+			return err
+		}
+	case len(r.Permit) > 0 || len(r.Deny) > 0 || len(r.PermitContinents) > 0 || len(r.DenyContinents) > 0:
+		activePolicy = policy.NewGeoPolicy("cli", &policy.GeoParams{
+			PermitCountries:  r.Permit,
+			DenyCountries:    r.Deny,
+			PermitContinents: r.PermitContinents,
+			DenyContinents:   r.DenyContinents,
+		})
+	default:
+		// Nothing to evaluate against
+		fmt.Println()
+		return nil
+	}
+
+	// Evaluate the IP addr against the policy
+	engine, err := policy.New(
+		policy.WithGeoIP(geoIP),
+		policy.WithPolicy(activePolicy),
+		policy.WithLogger(ctx.log),
+	)
+	if err != nil {
+		return err
+	}
+	decision := engine.Evaluate(&structs.AccoutCreatedEvent{
+		Event:  "account.created",
+		Object: structs.EventObject{Ip: ip.String()},
+	})
+	if decision.Err != nil {
+		return decision.Err
+	}
+
+	fmt.Printf("Decision:  %s\n", decision.Action)
+	if decision.Rule != "" {
+		fmt.Printf("Rule:      %s\n", decision.Rule)
+	}
+	fmt.Printf("Reason:    %s\n", decision.Reason)
+	fmt.Println()
+
+	return nil
+}
+
+// CheckCmd evaluates an account against a policy file
+type CheckCmd struct {
+	PolicyFile    string  `required:"" name:"policy" env:"MASTOBAN_POLICY_FILE" help:"Path to the policy file."`
+	DBFile        *string `name:"dbfile" env:"DBFILE" help:"Path to the GeoIP country database file."`
+	ASNDBFile     string  `name:"asndbfile" env:"ASNDBFILE" help:"Path to the GeoIP ASN database file (optional)."`
+	EventFile     string  `name:"event" help:"Path to an account.created webhook payload. Other account flags override its fields." type:"existingfile"`
+	IP            string  `name:"ip" help:"IP address of the account."`
+	Username      string  `name:"username" help:"Username of the account."`
+	Email         string  `name:"email" help:"Email address of the account."`
+	Locale        string  `name:"locale" help:"Locale of the account."`
+	InviteRequest string  `name:"invite" help:"Invite request text of the account."`
+	Approved      bool    `name:"approved" help:"Treat the account as already approved."`
+	Time          string  `name:"time" help:"Time of the event (RFC 3339) for rule schedules. Defaults to now."`
+	JSON          bool    `name:"json" help:"Print the decision record as JSON."`
+}
+
+// Run is the entry point for CheckCmd command
+func (r *CheckCmd) Run(ctx *Context) error {
+	// Load the account from the webhook payload, if provided
+	event := &structs.AccoutCreatedEvent{Event: "account.created"}
+	if r.EventFile != "" {
+		data, err := os.ReadFile(r.EventFile)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, event); err != nil {
+			return err
+		}
+	}
+
+	// Apply the account details from the cli args
+	if r.IP != "" {
+		event.Object.Ip = r.IP
+	}
+	if r.Username != "" {
+		event.Object.Username = r.Username
+	}
+	if r.Email != "" {
+		event.Object.Email = r.Email
+	}
+	if r.Locale != "" {
+		event.Object.Locale = r.Locale
+	}
+	if r.InviteRequest != "" {
+		event.Object.InviteRequest = r.InviteRequest
+	}
+	if r.Approved {
+		event.Object.Approved = true
+	}
+	if r.Time != "" {
+		t, err := time.Parse(time.RFC3339, r.Time)
+		if err != nil {
+			return err
+		}
+		event.CreatedAt = structs.Time{Time: t}
+	}
+
+	// Load and validate the policy file
+	activePolicy, err := policy.Load(r.PolicyFile)
+	if err != nil {
+		return err
+	}
+
+	// Use the GeoIP ASN database if provided
+	geoIPOpts := []geoip.Option{}
+	if r.ASNDBFile != "" {
+		geoIPOpts = append(geoIPOpts, geoip.WithASNDatabase(r.ASNDBFile))
+	}
+
+	// Create a new GeoIP DB instance
+	geoIP, err := geoip.New(r.DBFile, geoIPOpts...)
+	if err != nil {
+		return err
+	}
+
+	engine, err := policy.New(
+		policy.WithGeoIP(geoIP),
+		policy.WithPolicy(activePolicy),
+		policy.WithLogger(ctx.log),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Evaluate the account against the policy
+	decision := engine.Evaluate(event)
+	if r.JSON {
+		out, err := json.MarshalIndent(decision.Record(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	if decision.Input.GeoIP != nil {
+		fmt.Printf("IP Addr:   %s\n", decision.Input.GeoIP.IP)
+		fmt.Printf("Continent: %s\n", decision.Input.GeoIP.Continent)
+		fmt.Printf("Country:   %s\n", decision.Input.GeoIP.Country)
+		if decision.Input.GeoIP.ASN != 0 {
+			fmt.Printf("ASN:       AS%d (%s)\n", decision.Input.GeoIP.ASN, decision.Input.GeoIP.ASOrganization)
+		}
+	}
+	if decision.Input.IPClass != policy.ClassPublic {
+		fmt.Printf("IP Class:  %s\n", decision.Input.IPClass)
+	}
+	for _, ip := range decision.Record().IPs {
+		fmt.Printf("  %-39s  %-2s  %-15s  %s\n", ip.IP, ip.Country, ip.IPClass, ip.Action)
+	}
+	if decision.Err != nil {
+		return decision.Err
+	}
+
+	fmt.Printf("Decision:  %s\n", decision.Action)
+	if decision.DecidingIP != nil {
+		fmt.Printf("Via IP:    %s\n", decision.DecidingIP.Decision.Input.IP)
+	}
+	if decision.Rule != "" {
+		fmt.Printf("Rule:      %s\n", decision.Rule)
+	}
+	fmt.Printf("Reason:    %s\n", decision.Reason)
+	if decision.Match != "" {
+		fmt.Printf("Match:     %s\n", decision.Match)
+	}
+	if decision.Level != "" {
+		fmt.Printf("Level:     %s\n", decision.Level)
+	}
+	if decision.Text != "" {
+		fmt.Printf("Text:      %s\n", decision.Text)
+	}
+	if decision.IPBlock != nil {
+		fmt.Printf("IP Block:  %s\n", decision.IPBlock)
+	}
+	if decision.FollowUp != nil {
+		fmt.Printf("Follow-up: %s\n", decision.FollowUp)
+	}
+	if len(decision.Signals) > 0 {
+		fmt.Printf("Score:     %d\n", decision.Score)
+		for _, signal := range decision.Signals {
+			dryRun := ""
+			if signal.DryRun {
+				dryRun = " (dry run)"
+			}
+			fmt.Printf("  %+4d  %s: %s%s\n", signal.Weight, signal.Rule, signal.Reason, dryRun)
+		}
+	}
+	if decision.WouldAct != nil {
+		fmt.Printf("Would act: %s: %s\n", decision.WouldAct.Rule, decision.WouldAct.Reason)
+	}
 	fmt.Println()
 
 	return nil
@@ -101,13 +514,411 @@ func (r *SuspendCmd) Run(ctx *Context) error {
 	return nil
 }
 
+// ApproveCmd is the command to approve a pending account
+type ApproveCmd struct {
+	ID          string `required:"" name:"id" help:"ID of the account to approve."`
+	Instance    string `required:"" name:"instance" help:"Instance to approve the account on."`
+	AccessToken string `required:"" name:"token" help:"Access token to use to approve the account."`
+}
+
+// Run is the entry point for ApproveCmd command
+func (r *ApproveCmd) Run(ctx *Context) error {
+	// Create a new Mastoclient instance
+	mastodonClient, err := mastoclient.New(
+		mastoclient.WithInstance(r.Instance),       // Instance URL from CLI args
+		mastoclient.WithAccessToken(r.AccessToken), // Access Token from CLI args
+		mastoclient.WithLogger(ctx.log),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := mastodonClient.Approve(r.ID); err != nil {
+		return err
+	}
+
+	fmt.Printf("Approved account %s on %s\n", r.ID, r.Instance)
+
+	return nil
+}
+
+// RejectCmd is the command to reject a pending account
+type RejectCmd struct {
+	ID          string `required:"" name:"id" help:"ID of the account to reject."`
+	Instance    string `required:"" name:"instance" help:"Instance to reject the account on."`
+	AccessToken string `required:"" name:"token" help:"Access token to use to reject the account."`
+}
+
+// Run is the entry point for RejectCmd command
+func (r *RejectCmd) Run(ctx *Context) error {
+	// Create a new Mastoclient instance
+	mastodonClient, err := mastoclient.New(
+		mastoclient.WithInstance(r.Instance),       // Instance URL from CLI args
+		mastoclient.WithAccessToken(r.AccessToken), // Access Token from CLI args
+		mastoclient.WithLogger(ctx.log),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := mastodonClient.Reject(r.ID); err != nil {
+		return err
+	}
+
+	fmt.Printf("Rejected account %s on %s\n", r.ID, r.Instance)
+
+	return nil
+}
+
+// RevertCmd reverses the actions mastoban took, as recorded in the worker logs
+type RevertCmd struct {
+	Files       []string `arg:"" optional:"" name:"file" help:"Worker log files, one JSON event per line. Reads stdin when none are given." type:"existingfile"`
+	ID          string   `name:"id" help:"Reverse the last action taken against this account."`
+	PolicyName  string   `name:"policy-name" help:"Reverse the actions taken by this policy (the policy's name)."`
+	Since       string   `name:"since" help:"Reverse the actions taken at or after this time (RFC 3339)."`
+	Until       string   `name:"until" help:"Reverse the actions taken at or before this time (RFC 3339)."`
+	Instance    string   `required:"" name:"instance" help:"Instance the accounts are on."`
+	AccessToken string   `required:"" name:"token" help:"Access token to use to reverse the actions."`
+	DryRun      bool     `name:"dry-run" help:"List the actions that would be reversed without reversing them."`
+	KeepBlocks  bool     `name:"keep-blocks" help:"Leave the IP and email blocks mastoban created in place."`
+	Table       string   `name:"table" env:"MASTOBAN_FOLLOWUP_TABLE" help:"DynamoDB table holding the pending follow-up actions, which are cancelled."`
+	Region      string   `name:"region" env:"AWS_REGION" help:"AWS region of the DynamoDB table."`
+	Profile     string   `name:"profile" env:"AWS_PROFILE" help:"AWS profile to use."`
+}
+
+// Run is the entry point for RevertCmd command
+func (r *RevertCmd) Run(ctx *Context) error {
+	filter := &policy.EnforcementFilter{AccountID: r.ID, Policy: r.PolicyName}
+	if r.Since != "" {
+		t, err := time.Parse(time.RFC3339, r.Since)
+		if err != nil {
+			return err
+		}
+		filter.Since = t
+	}
+	if r.Until != "" {
+		t, err := time.Parse(time.RFC3339, r.Until)
+		if err != nil {
+			return err
+		}
+		filter.Until = t
+	}
+	if *filter == (policy.EnforcementFilter{}) {
+		return errors.New("one of --id, --policy-name, --since or --until is required")
+	}
+
+	readers := []io.Reader{}
+	for _, file := range r.Files {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	if len(readers) == 0 {
+		readers = append(readers, os.Stdin)
+	}
+
+	enforcements, err := policy.ReadEnforcements(io.MultiReader(readers...), filter)
+	if err != nil {
+		return err
+	}
+	if len(enforcements) == 0 {
+		fmt.Println("No actions to reverse")
+		return nil
+	}
+
+	// Create a new Mastoclient instance
+	mastodonClient, err := mastoclient.New(
+		mastoclient.WithInstance(r.Instance),       // Instance URL from CLI args
+		mastoclient.WithAccessToken(r.AccessToken), // Access Token from CLI args
+		mastoclient.WithLogger(ctx.log),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Pending follow-ups would undo the revert, so they are cancelled
+	var store followup.Store
+	if r.Table != "" {
+		store, err = followup.NewDynamoDBStore(
+			followup.WithTable(r.Table),
+			followup.WithRegion(r.Region),
+			followup.WithProfile(r.Profile),
+			followup.WithLogger(ctx.log),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, e := range enforcements {
+		d := e.Decision
+		action := d.Action
+		if d.Level != "" {
+			action += " (" + d.Level + ")"
+		}
+		fmt.Printf("%s  %-20s  %-30s  %-18s  %s", e.Time.Format(time.RFC3339), d.Account.Id, d.Account.Username, action, d.Rule)
+
+		blocks := []*revertBlock{}
+		if !r.KeepBlocks {
+			blocks = createdBlocks(&d)
+		}
+
+		switch {
+		case d.Action != string(policy.ActionAct) && len(blocks) == 0:
+			// Approvals can't be taken back, and rejected accounts are deleted
+			fmt.Println("  cannot be reversed")
+			continue
+		case r.DryRun:
+			if d.Action == string(policy.ActionAct) {
+				fmt.Println("  would reverse")
+			} else {
+				fmt.Println("  cannot be reversed")
+			}
+			for _, b := range blocks {
+				fmt.Printf("    would remove %s\n", b.name)
+			}
+			if d.Action == string(policy.ActionAct) {
+				lines, _ := cancelFollowUps(store, &d, true)
+				for _, line := range lines {
+					fmt.Println(line)
+				}
+			}
+			continue
+		}
+
+		ok := true
+		if d.Action == string(policy.ActionAct) {
+			// Cancel first, so a follow-up can't run after the revert
+			var lines []string
+			lines, ok = cancelFollowUps(store, &d, false)
+			if err := revertLevels(mastodonClient, d.Account.Id, e.Levels); err != nil {
+				fmt.Printf("  failed: %s\n", err)
+				ok = false
+			} else {
+				fmt.Println("  reversed")
+			}
+			for _, line := range lines {
+				fmt.Println(line)
+			}
+		} else {
+			fmt.Println("  cannot be reversed")
+		}
+
+		// Remove the blocks even if the account action failed to reverse,
+		// they were created for the account and are independent of it
+		for _, b := range blocks {
+			err := b.remove(mastodonClient)
+			switch {
+			case err == nil:
+				fmt.Printf("    removed %s\n", b.name)
+			case mastoclient.NotFound(err):
+				fmt.Printf("    %s was already removed\n", b.name)
+			default:
+				fmt.Printf("    failed to remove %s: %s\n", b.name, err)
+				ok = false
+			}
+		}
+		if !ok {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to reverse %d of %d actions", failed, len(enforcements))
+	}
+	return nil
+}
+
+// revertLevels lifts each of the suspend levels still applied to the account.
+func revertLevels(mastodonClient *mastoclient.Config, id string, levels []string) error {
+	for _, level := range levels {
+		if err := mastodonClient.Revert(id, level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cancelFollowUps deletes the pending follow-up actions for the account,
+// returning a line to print for each. Without a store, it can only warn
+// about a follow-up the decision record shows was scheduled. It reports
+// whether every follow-up was cancelled.
+func cancelFollowUps(store followup.Store, d *structs.Decision, dryRun bool) ([]string, bool) {
+	lines := []string{}
+	if store == nil {
+		if d.FollowUp != nil && d.FollowUp.Scheduled {
+			return append(lines, "    pending follow-up not cancelled, set --table"), false
+		}
+		return lines, true
+	}
+
+	actions, err := store.ForAccount(d.Account.Id)
+	if err != nil {
+		return append(lines, "    failed to find pending follow-ups: "+err.Error()), false
+	}
+
+	ok := true
+	for _, action := range actions {
+		name := "follow-up " + action.FollowUpLevel
+		if action.Lift {
+			name = "follow-up lift"
+		}
+		name += " due " + action.DueAt.Format(time.RFC3339)
+
+		if dryRun {
+			lines = append(lines, "    would cancel "+name)
+			continue
+		}
+		if err := store.Delete(action.ID); err != nil {
+			lines = append(lines, "    failed to cancel "+name+": "+err.Error())
+			ok = false
+			continue
+		}
+		lines = append(lines, "    cancelled "+name)
+	}
+	return lines, ok
+}
+
+// revertBlock is a Mastodon block mastoban created for an account, and
+// how to remove it.
+type revertBlock struct {
+	name   string
+	remove func(c *mastoclient.Config) error
+}
+
+// createdBlocks returns the blocks the decision record shows mastoban
+// created. Blocks that already existed are left alone.
+func createdBlocks(d *structs.Decision) []*revertBlock {
+	blocks := []*revertBlock{}
+	if b := d.IPBlock; b != nil && b.Created && b.ID != "" {
+		blocks = append(blocks, &revertBlock{
+			name:   "ip block " + b.IP,
+			remove: func(c *mastoclient.Config) error { return c.DeleteIPBlock(b.ID) },
+		})
+	}
+	if b := d.EmailBlock; b != nil && b.DomainCreated && b.DomainID != "" {
+		blocks = append(blocks, &revertBlock{
+			name:   "email domain block " + b.Domain,
+			remove: func(c *mastoclient.Config) error { return c.DeleteEmailDomainBlock(b.DomainID) },
+		})
+	}
+	if b := d.EmailBlock; b != nil && b.CanonicalCreated && b.CanonicalID != "" {
+		blocks = append(blocks, &revertBlock{
+			name:   "canonical email block " + d.Account.Email,
+			remove: func(c *mastoclient.Config) error { return c.DeleteCanonicalEmailBlock(b.CanonicalID) },
+		})
+	}
+	return blocks
+}
+
+// ServeCmd runs the follow-up actions that are due on an interval
+type ServeCmd struct {
+	Instance    string        `required:"" name:"instance" help:"Instance the accounts are on."`
+	AccessToken string        `required:"" name:"token" help:"Access token to use to run the follow-up actions."`
+	Table       string        `required:"" name:"table" env:"MASTOBAN_FOLLOWUP_TABLE" help:"DynamoDB table holding the pending follow-up actions."`
+	Region      string        `name:"region" env:"AWS_REGION" help:"AWS region of the DynamoDB table."`
+	Profile     string        `name:"profile" env:"AWS_PROFILE" help:"AWS profile to use."`
+	Interval    time.Duration `name:"interval" default:"5m" help:"How often to run the follow-up actions that are due."`
+	Once        bool          `name:"once" help:"Run the follow-up actions that are due once and exit."`
+}
+
+// Run is the entry point for ServeCmd command
+func (r *ServeCmd) Run(ctx *Context) error {
+	if r.Interval <= 0 {
+		return errors.New("--interval must be greater than zero")
+	}
+
+	store, err := followup.NewDynamoDBStore(
+		followup.WithTable(r.Table),
+		followup.WithRegion(r.Region),
+		followup.WithProfile(r.Profile),
+		followup.WithLogger(ctx.log),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Create a new Mastoclient instance
+	mastodonClient, err := mastoclient.New(
+		mastoclient.WithInstance(r.Instance),       // Instance URL from CLI args
+		mastoclient.WithAccessToken(r.AccessToken), // Access Token from CLI args
+		mastoclient.WithLogger(ctx.log),
+	)
+	if err != nil {
+		return err
+	}
+
+	executor, err := followup.New(
+		followup.WithStore(store),
+		followup.WithClient(mastodonClient),
+		followup.WithExecutorLogger(ctx.log),
+	)
+	if err != nil {
+		return err
+	}
+
+	runDue := func() {
+		results, err := executor.RunDue(time.Now())
+		if err != nil {
+			ctx.log.Error().Err(err).Msg("failed to run follow-up actions")
+			return
+		}
+		for _, result := range results {
+			fmt.Printf("%s  %-20s  %-30s  %-10s  %s", time.Now().Format(time.RFC3339), result.Action.AccountID, result.Action.Username, result.Outcome, result.Action.Rule)
+			if result.Err != nil {
+				fmt.Printf("  %s", result.Err)
+			}
+			fmt.Println()
+		}
+	}
+
+	runDue()
+	if r.Once {
+		return nil
+	}
+
+	// Run the executor in the background until interrupted
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(r.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				runDue()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	<-done
+
+	ctx.log.Info().Msg("stopped running follow-up actions")
+	return nil
+}
+
 // CLI is the main CLI struct
 type CLI struct {
 	// Global flags/args
 	LogLevel string `name:"loglevel" env:"LOGLEVEL" default:"info" enum:"panic,fatal,error,warn,info,debug,trace" help:"Set the log level."`
 
-	Lookup  LookupCmd  `cmd:"" help:"Parse an IP address and look it up in the GeoIP database."`
-	Suspend SuspendCmd `cmd:"" help:"Suspend an account."`
+	Account    AccountCmd    `cmd:"" help:"Fetch the current state of an account."`
+	Accounts   AccountsCmd   `cmd:"" help:"List the accounts on an instance, optionally evaluating each against a policy file."`
+	Approve    ApproveCmd    `cmd:"" help:"Approve a pending account."`
+	Check      CheckCmd      `cmd:"" help:"Validate a policy file and evaluate an account against it."`
+	Divergence DivergenceCmd `cmd:"" help:"Summarize shadow policy divergences from worker logs."`
+	Lookup     LookupCmd     `cmd:"" help:"Parse an IP address, look it up in the GeoIP database, and evaluate it against the policy."`
+	Reject     RejectCmd     `cmd:"" help:"Reject a pending account."`
+	Revert     RevertCmd     `cmd:"" help:"Reverse actions mastoban took, as recorded in the worker logs."`
+	Serve      ServeCmd      `cmd:"" help:"Run the scheduled follow-up actions as they fall due."`
+	Suspend    SuspendCmd    `cmd:"" help:"Suspend an account."`
 }
 
 func main() {
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/rmrfslashbin/mastoban/pkg/app"
+)
+
+// main is the entrypoint
+func main() {
+	// Run app.SchedulerHandler function
+	lambda.Start(app.SchedulerHandler)
+}
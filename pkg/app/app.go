@@ -4,8 +4,18 @@ const MODULE = "app"
 
 /* Environment variables requried by the Lambda function:
 GEOIP_DATABSE_PATH: path to the GeoIP database file provided by a Lambda layer.
+GEOIP_ASN_DATABASE_PATH: path to the GeoIP ASN database file provided by a Lambda layer. (optional)
 MASTODON_ACCESS_TOKEN: access token for the Mastodon account.
 MASTODON_INSTANCE_URL: URL of the Mastodon instance. (e.g. https://mastodon.social)
 MASTODON_SUSPEND_TEXT: text to include in the suspension notice.
+MASTOBAN_GEO_COUNTRY_PERMIT_LIST: comma separated list of country codes to permit.
+MASTOBAN_GEO_COUNTRY_DENY_LIST: comma separated list of country codes to deny. (optional, takes precedence)
+MASTOBAN_GEO_CONTINENT_PERMIT_LIST: comma separated list of continent codes to permit. (optional)
+MASTOBAN_GEO_CONTINENT_DENY_LIST: comma separated list of continent codes to deny. (optional)
+MASTOBAN_POLICY_FILE: path to the policy file. (optional, replaces the MASTOBAN_GEO_* lists)
+MASTOBAN_SHADOW_POLICY_FILE: path to a candidate policy file evaluated alongside the active policy, without enforcing. (optional)
+MASTOBAN_VELOCITY_TABLE: DynamoDB table for velocity rule and email domain block counts. (optional, counts are kept in memory if not set; required for email domain blocks)
+MASTOBAN_FOLLOWUP_TABLE: DynamoDB table for pending follow-up actions. (required by policies with follow_up rules)
+MASTOBAN_DRY_RUN: set to true to log and return the accounts that would be suspended without suspending them. (optional)
 PSK: pre-shared key, you know... for security.
 */
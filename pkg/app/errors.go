@@ -9,6 +9,11 @@ func errorFailedToSuspendUser() string {
 }
 */
 
+func errorInvalidEnvVar(varname string) string {
+	msg := "invalid value for environment variable: " + varname
+	return msg
+}
+
 func errorMessageEventNotSupported() string {
 	msg := "message event not supported"
 	return msg
@@ -19,11 +24,31 @@ func errorMissingPSK() string {
 	return msg
 }
 
+func errorNoFollowUpStore() string {
+	msg := "no follow-up store configured, set MASTOBAN_FOLLOWUP_TABLE"
+	return msg
+}
+
+func errorNoVelocityStore() string {
+	msg := "email domain blocks need a persistent velocity store, set MASTOBAN_VELOCITY_TABLE"
+	return msg
+}
+
 func errorPSKMismatch() string {
 	msg := "provided PSK is invalid"
 	return msg
 }
 
+func errorUnableToCreateFollowUpExecutor() string {
+	msg := "unable to create follow-up executor"
+	return msg
+}
+
+func errorUnableToCreateFollowUpStore() string {
+	msg := "unable to create follow-up store"
+	return msg
+}
+
 func errorUnableToCreateGeoIPInstance() string {
 	msg := "unable to create GeoIP instance"
 	return msg
@@ -34,16 +59,31 @@ func errorUnableToCreateMastoclientInstance() string {
 	return msg
 }
 
+func errorUnableToCreatePolicyEngine() string {
+	msg := "unable to create policy engine"
+	return msg
+}
+
 func errorUnableToCreateQueueInstance() string {
 	msg := "unable to create queue instance"
 	return msg
 }
 
+func errorUnableToCreateVelocityStore() string {
+	msg := "unable to create velocity store"
+	return msg
+}
+
 func errorUnableToFetchEnvVar(varname string) string {
 	msg := "unable to fetch environment variable: " + varname
 	return msg
 }
 
+func errorUnableToLoadPolicy() string {
+	msg := "unable to load policy"
+	return msg
+}
+
 /* might be deprecated
 func errorUnableToLookupIP() string {
 	msg := "unable to lookup IP in GeoIP database"
@@ -51,6 +91,11 @@ func errorUnableToLookupIP() string {
 }
 */
 
+func errorUnableToRunFollowUps() string {
+	msg := "unable to run follow-up actions"
+	return msg
+}
+
 func errorUnableToSendMessageToQueue() string {
 	msg := "unable to send message to queue"
 	return msg
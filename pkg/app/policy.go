@@ -0,0 +1,29 @@
+package app
+
+import (
+	"os"
+
+	"github.com/rmrfslashbin/mastoban/pkg/policy"
+)
+
+// loadPolicy loads the policy document named by MASTOBAN_POLICY_FILE.
+// When no policy file is configured, a single geo rule is built from the
+// MASTOBAN_GEO_COUNTRY_* and MASTOBAN_GEO_CONTINENT_* environment variables.
+func loadPolicy() (*policy.Policy, error) {
+	if policyFile := os.Getenv("MASTOBAN_POLICY_FILE"); policyFile != "" {
+		return policy.Load(policyFile)
+	}
+
+	params := &policy.GeoParams{
+		PermitCountries:  policy.ParseList(os.Getenv("MASTOBAN_GEO_COUNTRY_PERMIT_LIST")),
+		DenyCountries:    policy.ParseList(os.Getenv("MASTOBAN_GEO_COUNTRY_DENY_LIST")),
+		PermitContinents: policy.ParseList(os.Getenv("MASTOBAN_GEO_CONTINENT_PERMIT_LIST")),
+		DenyContinents:   policy.ParseList(os.Getenv("MASTOBAN_GEO_CONTINENT_DENY_LIST")),
+	}
+	if len(params.PermitCountries) == 0 && len(params.DenyCountries) == 0 &&
+		len(params.PermitContinents) == 0 && len(params.DenyContinents) == 0 {
+		return nil, &policy.InvalidPolicy{Msg: errorUnableToFetchEnvVar("MASTOBAN_GEO_COUNTRY_PERMIT_LIST")}
+	}
+
+	return policy.NewGeoPolicy("environment", params), nil
+}
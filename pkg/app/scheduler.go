@@ -0,0 +1,185 @@
+package app
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/rmrfslashbin/mastoban/pkg/followup"
+	"github.com/rmrfslashbin/mastoban/pkg/mastoclient"
+	"github.com/rmrfslashbin/mastoban/pkg/structs"
+	"github.com/rs/xid"
+	"github.com/rs/zerolog"
+)
+
+// SchedulerHandler is the entry point for the scheduled Lambda function
+// that runs the follow-up actions that are due.
+func SchedulerHandler(ctx context.Context, event events.CloudWatchEvent) (*structs.Output, error) {
+
+	// Set up the logger
+	log := zerolog.New(os.Stderr).With().Timestamp().Logger()
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+
+	// Fetch the Mastodon access token from the environment
+	accessToken := os.Getenv("MASTODON_ACCESS_TOKEN")
+	if accessToken == "" {
+		guid := xid.New()
+		log.Error().
+			Str("module", MODULE).
+			Str("function", "SchedulerHandler").
+			Str("process", "os.Getenv('MASTODON_ACCESS_TOKEN')").
+			Str("errRef", guid.String()).
+			Msg("Failed to get MASTODON_ACCESS_TOKEN from environment")
+		return &structs.Output{
+			Error: &structs.Err{
+				ErrRef: guid.String(), Msg: errorUnableToFetchEnvVar("MASTODON_ACCESS_TOKEN"),
+			},
+		}, nil
+	}
+
+	// Fetch the Mastodon instance URL from the environment
+	instanceURL := os.Getenv("MASTODON_INSTANCE_URL")
+	if instanceURL == "" {
+		guid := xid.New()
+		log.Error().
+			Str("module", MODULE).
+			Str("function", "SchedulerHandler").
+			Str("process", "os.Getenv('MASTODON_INSTANCE_URL')").
+			Str("errRef", guid.String()).
+			Msg("Failed to get MASTODON_INSTANCE_URL from environment")
+		return &structs.Output{
+			Error: &structs.Err{
+				ErrRef: guid.String(), Msg: errorUnableToFetchEnvVar("MASTODON_INSTANCE_URL"),
+			},
+		}, nil
+	}
+
+	// Fetch the follow-up table from the environment
+	followUpTable := os.Getenv("MASTOBAN_FOLLOWUP_TABLE")
+	if followUpTable == "" {
+		guid := xid.New()
+		log.Error().
+			Str("module", MODULE).
+			Str("function", "SchedulerHandler").
+			Str("process", "os.Getenv('MASTOBAN_FOLLOWUP_TABLE')").
+			Str("errRef", guid.String()).
+			Msg("Failed to get MASTOBAN_FOLLOWUP_TABLE from environment")
+		return &structs.Output{
+			Error: &structs.Err{
+				ErrRef: guid.String(), Msg: errorUnableToFetchEnvVar("MASTOBAN_FOLLOWUP_TABLE"),
+			},
+		}, nil
+	}
+
+	// Set up the follow-up store
+	followUpStore, err := followup.NewDynamoDBStore(
+		followup.WithTable(followUpTable),
+		followup.WithLogger(&log),
+	)
+	if err != nil {
+		guid := xid.New()
+		log.Error().
+			Err(err).
+			Str("module", MODULE).
+			Str("function", "SchedulerHandler").
+			Str("process", "followup.NewDynamoDBStore()").
+			Str("errRef", guid.String()).
+			Str("FollowUpTable", followUpTable).
+			Msg("Failed to create new follow-up store")
+		return &structs.Output{
+			Error: &structs.Err{
+				ErrRef: guid.String(), Msg: errorUnableToCreateFollowUpStore(),
+			},
+		}, nil
+	}
+
+	// Create a new mastoclient instance
+	mastodonClient, err := mastoclient.New(
+		mastoclient.WithInstance(instanceURL),
+		mastoclient.WithAccessToken(accessToken),
+		mastoclient.WithLogger(&log),
+	)
+	if err != nil {
+		guid := xid.New()
+		log.Error().
+			Err(err).
+			Str("module", MODULE).
+			Str("function", "SchedulerHandler").
+			Str("process", "mastoclient.New()").
+			Str("errRef", guid.String()).
+			Msg("Failed to create new mastoclient instance")
+		return &structs.Output{
+			Error: &structs.Err{
+				ErrRef: guid.String(), Msg: errorUnableToCreateMastoclientInstance(),
+			},
+		}, nil
+	}
+
+	// Run the follow-up actions that are due
+	executor, err := followup.New(
+		followup.WithStore(followUpStore),
+		followup.WithClient(mastodonClient),
+		followup.WithExecutorLogger(&log),
+	)
+	if err != nil {
+		guid := xid.New()
+		log.Error().
+			Err(err).
+			Str("module", MODULE).
+			Str("function", "SchedulerHandler").
+			Str("process", "followup.New()").
+			Str("errRef", guid.String()).
+			Msg("Failed to create new follow-up executor")
+		return &structs.Output{
+			Error: &structs.Err{
+				ErrRef: guid.String(), Msg: errorUnableToCreateFollowUpExecutor(),
+			},
+		}, nil
+	}
+
+	results, err := executor.RunDue(time.Now())
+	if err != nil {
+		guid := xid.New()
+		log.Error().
+			Err(err).
+			Str("module", MODULE).
+			Str("function", "SchedulerHandler").
+			Str("process", "executor.RunDue()").
+			Str("errRef", guid.String()).
+			Msg("Failed to run follow-up actions")
+		return &structs.Output{
+			Error: &structs.Err{
+				ErrRef: guid.String(), Msg: errorUnableToRunFollowUps(),
+			},
+		}, nil
+	}
+
+	return &structs.Output{
+		Status:    "ok",
+		FollowUps: FollowUpOutcomes(results),
+	}, nil
+}
+
+// FollowUpOutcomes converts the executor results for output.
+func FollowUpOutcomes(results []followup.Result) *[]structs.FollowUpOutcome {
+	outcomes := []structs.FollowUpOutcome{}
+	for _, result := range results {
+		outcome := structs.FollowUpOutcome{
+			ID:            result.Action.ID,
+			AccountID:     result.Action.AccountID,
+			Username:      result.Action.Username,
+			Rule:          result.Action.Rule,
+			Level:         result.Action.Level,
+			FollowUpLevel: result.Action.FollowUpLevel,
+			Lift:          result.Action.Lift,
+			DueAt:         result.Action.DueAt,
+			Outcome:       result.Outcome,
+		}
+		if result.Err != nil {
+			outcome.Error = result.Err.Error()
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return &outcomes
+}
@@ -3,18 +3,26 @@ package app
 import (
 	"context"
 	"encoding/json"
-	"net"
+	"errors"
 	"os"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/rmrfslashbin/mastoban/pkg/followup"
 	"github.com/rmrfslashbin/mastoban/pkg/geoip"
 	"github.com/rmrfslashbin/mastoban/pkg/mastoclient"
+	"github.com/rmrfslashbin/mastoban/pkg/policy"
 	"github.com/rmrfslashbin/mastoban/pkg/structs"
+	"github.com/rmrfslashbin/mastoban/pkg/velocity"
 	"github.com/rs/xid"
 	"github.com/rs/zerolog"
 )
 
+// memoryVelocityStore counts signups for velocity rules when no DynamoDB
+// table is configured. It lives as long as the warm Lambda instance.
+var memoryVelocityStore = velocity.NewMemoryStore()
+
 // WorkerHandler is the entry point for the Lambda function
 func WorkerHandler(ctx context.Context, request events.SQSEvent) (*structs.Output, error) {
 
@@ -39,8 +47,14 @@ func WorkerHandler(ctx context.Context, request events.SQSEvent) (*structs.Outpu
 		}, nil
 	}
 
+	// Use the GeoIP ASN database if provided
+	geoIpOpts := []geoip.Option{}
+	if geoIpASNDBPath := os.Getenv("GEOIP_ASN_DATABASE_PATH"); geoIpASNDBPath != "" {
+		geoIpOpts = append(geoIpOpts, geoip.WithASNDatabase(geoIpASNDBPath))
+	}
+
 	// Sert up the GeoIP database instance
-	geoIpDB, err := geoip.New(&geoIpDBPath)
+	geoIpDB, err := geoip.New(&geoIpDBPath, geoIpOpts...)
 	if err != nil {
 		guid := xid.New()
 		log.Error().
@@ -126,26 +140,182 @@ func WorkerHandler(ctx context.Context, request events.SQSEvent) (*structs.Outpu
 		}, nil
 	}
 
-	// none, sensitive, disable, silence, suspend
-	countryPermitString := os.Getenv("MASTOBAN_GEO_COUNTRY_PERMIT_LIST")
-	if suspendLevel == "" {
+	// Dry run mode evaluates accounts without suspending them
+	dryRun := false
+	if dryRunEnv := os.Getenv("MASTOBAN_DRY_RUN"); dryRunEnv != "" {
+		dryRun, err = strconv.ParseBool(dryRunEnv)
+		if err != nil {
+			guid := xid.New()
+			log.Error().
+				Err(err).
+				Str("module", MODULE).
+				Str("function", "WorkerHandler").
+				Str("process", "strconv.ParseBool('MASTOBAN_DRY_RUN')").
+				Str("errRef", guid.String()).
+				Str("MASTOBAN_DRY_RUN", dryRunEnv).
+				Msg("Failed to parse MASTOBAN_DRY_RUN from environment")
+			return &structs.Output{
+				Error: &structs.Err{
+					ErrRef: guid.String(), Msg: errorInvalidEnvVar("MASTOBAN_DRY_RUN"),
+				},
+			}, nil
+		}
+	}
+
+	// Load the policy document
+	activePolicy, err := loadPolicy()
+	if err != nil {
 		guid := xid.New()
 		log.Error().
+			Err(err).
 			Str("module", MODULE).
 			Str("function", "WorkerHandler").
-			Str("process", "os.Getenv('MASTOBAN_GEO_COUNTRY_PERMIT_LIST')").
+			Str("process", "loadPolicy()").
 			Str("errRef", guid.String()).
-			Msg("Failed to get MASTOBAN_GEO_COUNTRY_PERMIT_LIST from environment")
+			Msg("Failed to load policy")
 		return &structs.Output{
 			Error: &structs.Err{
-				ErrRef: guid.String(), Msg: errorUnableToFetchEnvVar("MASTOBAN_GEO_COUNTRY_PERMIT_LIST"),
+				ErrRef: guid.String(), Msg: errorUnableToLoadPolicy(),
 			},
 		}, nil
 	}
 
-	countriesPermitList := make(map[string]struct{})
-	for _, country := range strings.Split(countryPermitString, ",") {
-		countriesPermitList[strings.ToUpper(strings.TrimSpace(country))] = struct{}{}
+	// Count signups for velocity rules in DynamoDB if a table is configured
+	var velocityStore velocity.Store = memoryVelocityStore
+	if velocityTable := os.Getenv("MASTOBAN_VELOCITY_TABLE"); velocityTable != "" {
+		velocityStore, err = velocity.NewDynamoDBStore(
+			velocity.WithTable(velocityTable),
+			velocity.WithLogger(&log),
+		)
+		if err != nil {
+			guid := xid.New()
+			log.Error().
+				Err(err).
+				Str("module", MODULE).
+				Str("function", "WorkerHandler").
+				Str("process", "velocity.NewDynamoDBStore()").
+				Str("errRef", guid.String()).
+				Str("VelocityTable", velocityTable).
+				Msg("Failed to create new velocity store")
+			return &structs.Output{
+				Error: &structs.Err{
+					ErrRef: guid.String(), Msg: errorUnableToCreateVelocityStore(),
+				},
+			}, nil
+		}
+	}
+
+	// Email domain blocks count suspensions across invocations, which the
+	// in-memory store can't do
+	if activePolicy.BlocksEmailDomains() && os.Getenv("MASTOBAN_VELOCITY_TABLE") == "" {
+		guid := xid.New()
+		log.Error().
+			Str("module", MODULE).
+			Str("function", "WorkerHandler").
+			Str("process", "activePolicy.BlocksEmailDomains()").
+			Str("errRef", guid.String()).
+			Str("Policy", activePolicy.Name).
+			Msg("Email domain blocks need a persistent velocity store")
+		return &structs.Output{
+			Error: &structs.Err{
+				ErrRef: guid.String(), Msg: errorNoVelocityStore(),
+			},
+		}, nil
+	}
+
+	// Follow-ups are run later by the scheduler, which reads them from the table
+	if activePolicy.SchedulesFollowUps() && os.Getenv("MASTOBAN_FOLLOWUP_TABLE") == "" {
+		guid := xid.New()
+		log.Error().
+			Str("module", MODULE).
+			Str("function", "WorkerHandler").
+			Str("process", "activePolicy.SchedulesFollowUps()").
+			Str("errRef", guid.String()).
+			Str("Policy", activePolicy.Name).
+			Msg("Follow-up actions need a follow-up store")
+		return &structs.Output{
+			Error: &structs.Err{
+				ErrRef: guid.String(), Msg: errorNoFollowUpStore(),
+			},
+		}, nil
+	}
+
+	// Set up the store for follow-up actions, if a table is configured
+	var followUpStore followup.Store
+	if followUpTable := os.Getenv("MASTOBAN_FOLLOWUP_TABLE"); followUpTable != "" {
+		followUpStore, err = followup.NewDynamoDBStore(
+			followup.WithTable(followUpTable),
+			followup.WithLogger(&log),
+		)
+		if err != nil {
+			guid := xid.New()
+			log.Error().
+				Err(err).
+				Str("module", MODULE).
+				Str("function", "WorkerHandler").
+				Str("process", "followup.NewDynamoDBStore()").
+				Str("errRef", guid.String()).
+				Str("FollowUpTable", followUpTable).
+				Msg("Failed to create new follow-up store")
+			return &structs.Output{
+				Error: &structs.Err{
+					ErrRef: guid.String(), Msg: errorUnableToCreateFollowUpStore(),
+				},
+			}, nil
+		}
+	}
+
+	// Set up the policy engine
+	engine, err := policy.New(
+		policy.WithGeoIP(geoIpDB),
+		policy.WithPolicy(activePolicy),
+		policy.WithDryRun(dryRun),
+		policy.WithVelocityStore(velocityStore),
+		policy.WithLogger(&log),
+	)
+	if err != nil {
+		guid := xid.New()
+		log.Error().
+			Err(err).
+			Str("module", MODULE).
+			Str("function", "WorkerHandler").
+			Str("process", "policy.New()").
+			Str("errRef", guid.String()).
+			Str("Policy", activePolicy.Name).
+			Msg("Failed to create new policy engine")
+		return &structs.Output{
+			Error: &structs.Err{
+				ErrRef: guid.String(), Msg: errorUnableToCreatePolicyEngine(),
+			},
+		}, nil
+	}
+
+	// Set up the shadow policy engine, if configured. The shadow policy never
+	// enforces, so a shadow policy that fails to load is logged and skipped.
+	var shadowEngine *policy.Engine
+	if shadowPolicyFile := os.Getenv("MASTOBAN_SHADOW_POLICY_FILE"); shadowPolicyFile != "" {
+		shadowPolicy, err := policy.Load(shadowPolicyFile)
+		if err == nil {
+			shadowEngine, err = policy.New(
+				policy.WithGeoIP(geoIpDB),
+				policy.WithPolicy(shadowPolicy),
+				policy.WithDryRun(dryRun),
+				policy.WithVelocityStore(velocityStore),
+				policy.WithVelocityReadOnly(),
+				policy.WithLogger(&log),
+			)
+		}
+		if err != nil {
+			guid := xid.New()
+			log.Error().
+				Err(err).
+				Str("module", MODULE).
+				Str("function", "WorkerHandler").
+				Str("process", "policy.New() shadow policy").
+				Str("errRef", guid.String()).
+				Str("ShadowPolicyFile", shadowPolicyFile).
+				Msg("Failed to load shadow policy. Continuing without it.")
+		}
 	}
 
 	// Create a new mastoclient instance
@@ -170,7 +340,10 @@ func WorkerHandler(ctx context.Context, request events.SQSEvent) (*structs.Outpu
 		}, nil
 	}
 
-	impactedUsers := []structs.EventObject{}
+	impactedUsers := []structs.User{}
+	wouldActUsers := []structs.WouldAct{}
+	decisions := []structs.Decision{}
+	divergences := []structs.Divergence{}
 
 	for i := range request.Records {
 		message := &structs.AccoutCreatedEvent{}
@@ -187,61 +360,143 @@ func WorkerHandler(ctx context.Context, request events.SQSEvent) (*structs.Outpu
 			continue
 		}
 
-		// Parse the IP address from the request
-		userIP := net.ParseIP(message.Object.Ip)
-		if userIP == nil {
+		// Evaluate the account against the policy
+		decision := engine.Evaluate(message)
+		record := decision.Record()
+
+		// Compare the decision with the shadow policy
+		if shadowEngine != nil {
+			shadowDecision := shadowEngine.Evaluate(message)
+			if policy.Diverges(decision, shadowDecision, suspendLevel) {
+				divergence := structs.Divergence{Active: *record, Shadow: *shadowDecision.Record()}
+
+				guid := xid.New()
+				log.Warn().
+					Str("module", MODULE).
+					Str("function", "WorkerHandler").
+					Str("process", "shadowEngine.Evaluate()").
+					Str("errRef", guid.String()).
+					Str("UserID", message.Object.Id).
+					Interface("Divergence", divergence).
+					Msg("Shadow policy diverged from the active policy")
+				divergences = append(divergences, divergence)
+			}
+		}
+		if decision.Action == policy.ActionError {
 			guid := xid.New()
 			log.Error().
+				Err(decision.Err).
 				Str("module", MODULE).
 				Str("function", "WorkerHandler").
-				Str("process", "net.ParseIP()").
-				Str("IP", message.Object.Ip).
+				Str("process", "engine.Evaluate()").
 				Str("errRef", guid.String()).
-				Msg("Filed to parse IP address")
+				Str("UserID", message.Object.Id).
+				Interface("Decision", record).
+				Msg("Failed to evaluate account against the policy")
+			decisions = append(decisions, *record)
 			continue
 		}
 
-		// Lookup the IP address in the GeoIP database
-		ipData, err := geoIpDB.Lookup(userIP)
-		if err != nil {
+		// Dry run rules report what they would have done
+		if record.WouldAct != nil {
+			if record.WouldAct.Level == "" && record.WouldAct.Outcome == string(policy.ActionAct) {
+				record.WouldAct.Level = suspendLevel
+			}
+			wouldActUsers = append(wouldActUsers, structs.WouldAct{
+				User:   message.Object,
+				Action: record.WouldAct.Outcome,
+				Rule:   record.WouldAct.Rule,
+				Level:  record.WouldAct.Level,
+				Reason: record.WouldAct.Reason,
+			})
+		}
+
+		// Pending accounts are approved or rejected rather than suspended.
+		// The engine checks the payload's approved flag, and acts on
+		// approved accounts instead of rejecting them.
+		if decision.Action == policy.ActionApprove || decision.Action == policy.ActionReject {
+			process, msg := "mastodonClient.Approve()", "Policy approved the account. Account Approved!"
+			enforce := mastodonClient.Approve
+			if decision.Action == policy.ActionReject {
+				process, msg = "mastodonClient.Reject()", "Policy rejected the account. Account Rejected!"
+				enforce = mastodonClient.Reject
+			}
+
+			if err := enforce(message.Object.Id); err != nil {
+				guid := xid.New()
+				log.Error().
+					Err(err).
+					Str("module", MODULE).
+					Str("function", "WorkerHandler").
+					Str("process", process).
+					Str("UserID", message.Object.Id).
+					Str("errRef", guid.String()).
+					Interface("Decision", record).
+					Msg("Failed to " + string(decision.Action) + " user")
+				decisions = append(decisions, *record)
+				continue
+			}
+			record.Enforced = true
+			blockIP(&log, mastodonClient, decision, record)
+			blockEmail(&log, mastodonClient, engine, decision, record)
+
 			guid := xid.New()
-			log.Error().
-				Err(err).
+			log.Info().
 				Str("module", MODULE).
 				Str("function", "WorkerHandler").
-				Str("process", "geoIpDB.Lookup()").
-				Str("IP", userIP.String()).
+				Str("process", "engine.Evaluate()").
 				Str("errRef", guid.String()).
-				Msg("Filed to lookup IP address in GeoIP database")
+				Str("UserID", message.Object.Id).
+				Interface("Decision", record).
+				Msg(msg)
+			decisions = append(decisions, *record)
+
+			if decision.Action == policy.ActionReject {
+				impactedUsers = append(impactedUsers, structs.User{
+					Username:  message.Object.Username,
+					Id:        message.Object.Id,
+					Domain:    message.Object.Domain,
+					Email:     message.Object.Email,
+					CreatedAt: message.Object.CreatedAt.Time,
+				})
+			}
 			continue
 		}
 
-		if _, ok := countriesPermitList[ipData.Country]; ok {
-			//if ipData.Country == "US" {
+		if decision.Action != policy.ActionAct {
+			msg := "Policy did not call for action. Doing nothing."
+			if record.WouldAct != nil {
+				msg = "Dry run. Policy would have called for action."
+			}
+
 			guid := xid.New()
 			log.Info().
 				Str("module", MODULE).
 				Str("function", "WorkerHandler").
-				Str("process", "geoIpDB.Lookup()").
+				Str("process", "engine.Evaluate()").
 				Str("errRef", guid.String()).
-				Str("IP", ipData.IP.String()).
-				Str("Country", ipData.Country).
-				Str("Continent", ipData.Continent).
 				Str("UserID", message.Object.Id).
-				Str("Username", message.Object.Username).
-				Str("Domain", message.Object.Domain).
-				Str("Email", message.Object.Email).
-				Str("CreatedAt", message.Object.CreatedAt).
-				Msg("IP is the permitted country list. Doing nothing.")
+				Interface("Decision", record).
+				Msg(msg)
+			decisions = append(decisions, *record)
 			continue
 		}
 
+		// Rules and risk score thresholds can set their own suspend level and text
+		if record.Level == "" {
+			record.Level = suspendLevel
+		}
+		text := suspendText
+		if decision.Text != "" {
+			text = decision.Text
+		}
+
 		// Suspend the user!
 		err = mastodonClient.Suspend(
 			&mastoclient.SuspendInput{
 				ID:           message.Object.Id,
-				SuspendText:  suspendText,
-				SuspendLevel: suspendLevel})
+				SuspendText:  text,
+				SuspendLevel: record.Level})
 		if err != nil {
 			guid := xid.New()
 			log.Error().
@@ -251,37 +506,218 @@ func WorkerHandler(ctx context.Context, request events.SQSEvent) (*structs.Outpu
 				Str("process", "mastodonClient.Suspend()").
 				Str("UserID", message.Object.Id).
 				Str("errRef", guid.String()).
+				Interface("Decision", record).
 				Msg("Failed to suspend user")
+			decisions = append(decisions, *record)
 			continue
 		}
+		record.Enforced = true
+		blockIP(&log, mastodonClient, decision, record)
+		blockEmail(&log, mastodonClient, engine, decision, record)
+		scheduleFollowUp(&log, followUpStore, decision, record, text)
 
 		// Log the details and return
 		guid := xid.New()
 		log.Info().
 			Str("module", MODULE).
 			Str("function", "WorkerHandler").
-			Str("process", "geoIpDB.Lookup()").
+			Str("process", "engine.Evaluate()").
 			Str("errRef", guid.String()).
-			Str("IP", ipData.IP.String()).
-			Str("Country", ipData.Country).
-			Str("Continent", ipData.Continent).
 			Str("UserID", message.Object.Id).
-			Str("Username", message.Object.Username).
-			Str("Domain", message.Object.Domain).
-			Str("Email", message.Object.Email).
-			Str("CreatedAt", message.Object.CreatedAt).
-			Msg("IP is not from the county permit list. Account Suspended!")
+			Interface("Decision", record).
+			Msg("Policy called for action. Account Suspended!")
+		decisions = append(decisions, *record)
 
-		impactedUsers = append(impactedUsers, structs.EventObject{
+		impactedUsers = append(impactedUsers, structs.User{
 			Username:  message.Object.Username,
 			Id:        message.Object.Id,
 			Domain:    message.Object.Domain,
 			Email:     message.Object.Email,
-			CreatedAt: message.Object.CreatedAt,
+			CreatedAt: message.Object.CreatedAt.Time,
 		})
 	}
+
+	// Dry run suppressed every action
+	status := "suspended"
+	if len(wouldActUsers) > 0 && !enforcedAny(decisions) {
+		status = "dry_run"
+	}
+
 	return &structs.Output{
-		Status: "suspended",
-		Users:  &impactedUsers,
+		Status:      status,
+		Users:       &impactedUsers,
+		WouldAct:    &wouldActUsers,
+		Decisions:   &decisions,
+		Divergences: &divergences,
 	}, nil
 }
+
+// enforcedAny reports whether any of the decisions was enforced.
+func enforcedAny(decisions []structs.Decision) bool {
+	for i := range decisions {
+		if decisions[i].Enforced {
+			return true
+		}
+	}
+	return false
+}
+
+// blockIP creates the IP block asked for by the decision and records the outcome.
+func blockIP(log *zerolog.Logger, mastodonClient *mastoclient.Config, decision *policy.Decision, record *structs.Decision) {
+	if decision.IPBlock == nil || record.IPBlock == nil {
+		return
+	}
+
+	block, created, err := mastodonClient.EnsureIPBlock(&mastoclient.IPBlockInput{
+		IP:        decision.IPBlock.Network.String(),
+		Severity:  decision.IPBlock.Severity,
+		Comment:   "mastoban: " + decision.Rule + ": " + decision.Reason,
+		ExpiresIn: decision.IPBlock.ExpiresIn,
+	})
+	if err != nil {
+		record.IPBlock.Error = err.Error()
+
+		guid := xid.New()
+		log.Error().
+			Err(err).
+			Str("module", MODULE).
+			Str("function", "WorkerHandler").
+			Str("process", "mastodonClient.EnsureIPBlock()").
+			Str("UserID", decision.Input.Event.Object.Id).
+			Str("errRef", guid.String()).
+			Str("ip_block", record.IPBlock.IP).
+			Msg("Failed to create IP block")
+		return
+	}
+
+	record.IPBlock.Created = created
+	if created {
+		record.IPBlock.ID = block.ID
+	} else {
+		record.IPBlock.Existing = block.IP
+	}
+}
+
+// blockEmail creates the email blocks asked for by the decision and records the outcome.
+func blockEmail(log *zerolog.Logger, mastodonClient *mastoclient.Config, engine *policy.Engine, decision *policy.Decision, record *structs.Decision) {
+	block, err := engine.EmailBlock(decision, record.Level)
+	if err != nil {
+		guid := xid.New()
+		log.Error().
+			Err(err).
+			Str("module", MODULE).
+			Str("function", "WorkerHandler").
+			Str("process", "engine.EmailBlock()").
+			Str("UserID", decision.Input.Event.Object.Id).
+			Str("errRef", guid.String()).
+			Msg("Failed to count the suspension against the email domain")
+		return
+	}
+	if block == nil {
+		return
+	}
+
+	record.EmailBlock = &structs.EmailBlock{
+		Domain:    block.Domain,
+		Count:     block.Count,
+		Canonical: block.Email != "",
+		DryRun:    block.DryRun,
+	}
+	if block.DryRun {
+		if block.Domain != "" {
+			guid := xid.New()
+			log.Info().
+				Str("module", MODULE).
+				Str("function", "WorkerHandler").
+				Str("process", "engine.EmailBlock()").
+				Str("UserID", decision.Input.Event.Object.Id).
+				Str("errRef", guid.String()).
+				Str("email_domain", block.Domain).
+				Int("count", block.Count).
+				Msg("Dry run. Email domain would have been blocked.")
+		}
+		return
+	}
+
+	fail := func(process string, err error, msg string) {
+		if record.EmailBlock.Error == "" {
+			record.EmailBlock.Error = err.Error()
+		}
+
+		guid := xid.New()
+		log.Error().
+			Err(err).
+			Str("module", MODULE).
+			Str("function", "WorkerHandler").
+			Str("process", process).
+			Str("UserID", decision.Input.Event.Object.Id).
+			Str("errRef", guid.String()).
+			Msg(msg)
+	}
+
+	if block.Domain != "" {
+		domainBlock, created, err := mastodonClient.EnsureEmailDomainBlock(block.Domain)
+		if err != nil {
+			fail("mastodonClient.EnsureEmailDomainBlock()", err, "Failed to create email domain block")
+		}
+		record.EmailBlock.DomainCreated = created
+		if created {
+			record.EmailBlock.DomainID = domainBlock.ID
+		}
+	}
+	if block.Email != "" {
+		canonicalBlock, created, err := mastodonClient.EnsureCanonicalEmailBlock(block.Email)
+		if err != nil {
+			fail("mastodonClient.EnsureCanonicalEmailBlock()", err, "Failed to create canonical email block")
+		}
+		record.EmailBlock.CanonicalCreated = created
+		if created {
+			record.EmailBlock.CanonicalID = canonicalBlock.ID
+		}
+	}
+}
+
+// scheduleFollowUp stores the follow-up action asked for by the decision and records the outcome.
+func scheduleFollowUp(log *zerolog.Logger, store followup.Store, decision *policy.Decision, record *structs.Decision, text string) {
+	if decision.FollowUp == nil || record.FollowUp == nil {
+		return
+	}
+
+	now := time.Now()
+	dueAt := now.Add(decision.FollowUp.After)
+	record.FollowUp.DueAt = &dueAt
+
+	var err error
+	if store == nil {
+		err = errors.New(errorNoFollowUpStore())
+	} else {
+		err = store.Put(&followup.Action{
+			ID:            xid.New().String(),
+			AccountID:     decision.Input.Event.Object.Id,
+			Username:      decision.Input.Event.Object.Username,
+			Level:         record.Level,
+			FollowUpLevel: decision.FollowUp.Level,
+			Lift:          decision.FollowUp.Lift,
+			Text:          text,
+			Rule:          decision.Rule,
+			Policy:        decision.Policy,
+			CreatedAt:     now,
+			DueAt:         dueAt,
+		})
+	}
+	if err != nil {
+		record.FollowUp.Error = err.Error()
+
+		guid := xid.New()
+		log.Error().
+			Err(err).
+			Str("module", MODULE).
+			Str("function", "WorkerHandler").
+			Str("process", "followUpStore.Put()").
+			Str("UserID", decision.Input.Event.Object.Id).
+			Str("errRef", guid.String()).
+			Msg("Failed to schedule follow-up action")
+		return
+	}
+	record.FollowUp.Scheduled = true
+}
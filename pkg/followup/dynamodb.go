@@ -0,0 +1,221 @@
+package followup
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/rs/zerolog"
+)
+
+// DynamoDBOption for the DynamoDB store
+type DynamoDBOption func(s *DynamoDBStore)
+
+// DynamoDBStore keeps pending actions in a DynamoDB table, so the worker
+// and the executor can run in different processes. The table needs a
+// string partition key named "id". Pending actions are few, so Due and
+// ForAccount scan the table rather than needing an index.
+type DynamoDBStore struct {
+	table   string
+	region  string
+	profile string
+	log     *zerolog.Logger
+	db      *dynamodb.Client
+}
+
+// NewDynamoDBStore creates a new DynamoDBStore.
+func NewDynamoDBStore(opts ...DynamoDBOption) (*DynamoDBStore, error) {
+	s := &DynamoDBStore{}
+
+	// apply the list of options to DynamoDBStore
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.table == "" {
+		return nil, &NoTable{}
+	}
+
+	if s.region == "" {
+		s.region = os.Getenv("AWS_REGION")
+	}
+
+	awsConfig, err := config.LoadDefaultConfig(context.TODO(), func(o *config.LoadOptions) error {
+		o.Region = s.region
+		if s.profile != "" {
+			o.SharedConfigProfile = s.profile
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// set up logger if not provided
+	if s.log == nil {
+		log := zerolog.New(os.Stderr).With().Timestamp().Logger()
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		s.log = &log
+	}
+
+	s.db = dynamodb.NewFromConfig(awsConfig)
+	return s, nil
+}
+
+// WithLogger sets the logger to use
+func WithLogger(log *zerolog.Logger) DynamoDBOption {
+	return func(s *DynamoDBStore) {
+		s.log = log
+	}
+}
+
+// WithProfile sets the AWS profile to use
+func WithProfile(profile string) DynamoDBOption {
+	return func(s *DynamoDBStore) {
+		s.profile = profile
+	}
+}
+
+// WithRegion sets the AWS region to use
+func WithRegion(region string) DynamoDBOption {
+	return func(s *DynamoDBStore) {
+		s.region = region
+	}
+}
+
+// WithTable sets the DynamoDB table name
+func WithTable(table string) DynamoDBOption {
+	return func(s *DynamoDBStore) {
+		s.table = table
+	}
+}
+
+// Put adds or replaces a pending action. The action is stored as JSON,
+// alongside its due time for Due to filter on.
+func (s *DynamoDBStore) Put(action *Action) error {
+	data, err := json.Marshal(action)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item: map[string]types.AttributeValue{
+			"id":     &types.AttributeValueMemberS{Value: action.ID},
+			"due_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(action.DueAt.Unix(), 10)},
+			"action": &types.AttributeValueMemberS{Value: string(data)},
+		},
+	})
+	if err != nil {
+		s.log.Error().
+			Str("process", "followup::Put::dynamodb.PutItem()").
+			Str("table", s.table).
+			Str("id", action.ID).
+			Err(err).
+			Msg("error storing follow-up action")
+		return err
+	}
+	return nil
+}
+
+// Due returns the pending actions due at or before now, oldest first.
+func (s *DynamoDBStore) Due(now time.Time) ([]Action, error) {
+	return s.scan("followup::Due", &dynamodb.ScanInput{
+		TableName:        aws.String(s.table),
+		FilterExpression: aws.String("#due_at <= :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#due_at": "due_at",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+	})
+}
+
+// ForAccount returns the pending actions for an account, oldest first.
+// The account is only in the stored JSON, so the actions are filtered
+// after scanning the table.
+func (s *DynamoDBStore) ForAccount(accountID string) ([]Action, error) {
+	actions, err := s.scan("followup::ForAccount", &dynamodb.ScanInput{
+		TableName: aws.String(s.table),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []Action{}
+	for _, action := range actions {
+		if action.AccountID == accountID {
+			pending = append(pending, action)
+		}
+	}
+	return pending, nil
+}
+
+// scan returns the actions matched by the scan, oldest first.
+func (s *DynamoDBStore) scan(process string, input *dynamodb.ScanInput) ([]Action, error) {
+	actions := []Action{}
+	for {
+		out, err := s.db.Scan(context.TODO(), input)
+		if err != nil {
+			s.log.Error().
+				Str("process", process+"::dynamodb.Scan()").
+				Str("table", s.table).
+				Err(err).
+				Msg("error scanning follow-up actions")
+			return nil, err
+		}
+
+		for _, item := range out.Items {
+			value, ok := item["action"].(*types.AttributeValueMemberS)
+			if !ok {
+				continue
+			}
+			action := Action{}
+			if err := json.Unmarshal([]byte(value.Value), &action); err != nil {
+				s.log.Error().
+					Str("process", process+"::json.Unmarshal()").
+					Str("table", s.table).
+					Err(err).
+					Msg("error parsing follow-up action")
+				continue
+			}
+			actions = append(actions, action)
+		}
+
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+
+	sortDue(actions)
+	return actions, nil
+}
+
+// Delete removes a pending action.
+func (s *DynamoDBStore) Delete(id string) error {
+	_, err := s.db.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+	})
+	if err != nil {
+		s.log.Error().
+			Str("process", "followup::Delete::dynamodb.DeleteItem()").
+			Str("table", s.table).
+			Str("id", id).
+			Err(err).
+			Msg("error deleting follow-up action")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package followup
+
+// NoClient is returned when the executor is created without a Mastodon client.
+type NoClient struct {
+	Err error
+	Msg string
+}
+
+// Error returns the error message
+func (e *NoClient) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = "no mastodon client. use WithClient()"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// NoStore is returned when the executor is created without a store.
+type NoStore struct {
+	Err error
+	Msg string
+}
+
+// Error returns the error message
+func (e *NoStore) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = "no follow-up store. use WithStore()"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// NoTable is returned when the DynamoDB store is created without a table name.
+type NoTable struct {
+	Err error
+	Msg string
+}
+
+// Error returns the error message
+func (e *NoTable) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = "no table name. use WithTable()"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
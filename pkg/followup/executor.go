@@ -0,0 +1,199 @@
+package followup
+
+import (
+	"os"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/mastoclient"
+	"github.com/rmrfslashbin/mastoban/pkg/structs"
+	"github.com/rs/zerolog"
+)
+
+// Outcomes of running a follow-up action
+const (
+	OutcomeEscalated = "escalated"
+	OutcomeLifted    = "lifted"
+	OutcomeCleared   = "cleared"
+	OutcomeGone      = "gone"
+	OutcomeFailed    = "failed"
+)
+
+// Option for the executor
+type Option func(e *Executor)
+
+// Executor runs the follow-up actions that are due.
+type Executor struct {
+	log    *zerolog.Logger
+	store  Store
+	client *mastoclient.Config
+}
+
+// Result is the outcome of running one follow-up action.
+type Result struct {
+	Action  Action
+	Outcome string
+	Err     error
+}
+
+// New creates a new Executor.
+func New(opts ...Option) (*Executor, error) {
+	e := &Executor{}
+
+	// apply the list of options to Executor
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if e.store == nil {
+		return nil, &NoStore{}
+	}
+	if e.client == nil {
+		return nil, &NoClient{}
+	}
+
+	// set up logger if not provided
+	if e.log == nil {
+		log := zerolog.New(os.Stderr).With().Timestamp().Logger()
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		e.log = &log
+	}
+	return e, nil
+}
+
+// WithClient sets the Mastodon client used to run the actions
+func WithClient(client *mastoclient.Config) Option {
+	return func(e *Executor) {
+		e.client = client
+	}
+}
+
+// WithExecutorLogger sets the logger to use
+func WithExecutorLogger(log *zerolog.Logger) Option {
+	return func(e *Executor) {
+		e.log = log
+	}
+}
+
+// WithStore sets the store holding the pending actions
+func WithStore(store Store) Option {
+	return func(e *Executor) {
+		e.store = store
+	}
+}
+
+// RunDue runs every pending action due at or before now. Each action first
+// checks the account's current state: if the account is gone, or no longer
+// has the level mastoban applied because a moderator cleared or changed it,
+// the action is dropped. Actions that fail are kept and retried on the next run.
+func (e *Executor) RunDue(now time.Time) ([]Result, error) {
+	due, err := e.store.Due(now)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Result{}
+	for _, action := range due {
+		result := Result{Action: action}
+		var account *structs.AdminAccount
+		result.Outcome, account, result.Err = e.run(&action)
+
+		event := e.log.Info()
+		if result.Err != nil {
+			event = e.log.Error().Err(result.Err)
+		}
+		event = event.
+			Str("process", "followup::RunDue").
+			Str("id", action.ID).
+			Str("UserID", action.AccountID).
+			Str("rule", action.Rule).
+			Str("outcome", result.Outcome)
+		if record := enforcement(&action, account, result.Outcome); record != nil {
+			event = event.Interface("Decision", record)
+		}
+		event.Msg("Ran follow-up action")
+
+		if result.Outcome != OutcomeFailed {
+			if err := e.store.Delete(action.ID); err != nil {
+				result.Err = err
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// run runs one follow-up action and returns its outcome, along with
+// the account as it was before the action, if it was fetched.
+func (e *Executor) run(action *Action) (string, *structs.AdminAccount, error) {
+	account, err := e.client.GetAccount(action.AccountID)
+	if err != nil {
+		if mastoclient.NotFound(err) {
+			return OutcomeGone, nil, nil
+		}
+		return OutcomeFailed, nil, err
+	}
+	if !HasLevel(account, action.Level) {
+		return OutcomeCleared, account, nil
+	}
+
+	if action.Lift {
+		if err := e.client.Revert(action.AccountID, action.Level); err != nil {
+			return OutcomeFailed, account, err
+		}
+		return OutcomeLifted, account, nil
+	}
+
+	err = e.client.Suspend(&mastoclient.SuspendInput{
+		ID:           action.AccountID,
+		SuspendText:  action.Text,
+		SuspendLevel: action.FollowUpLevel,
+	})
+	if err != nil {
+		return OutcomeFailed, account, err
+	}
+	return OutcomeEscalated, account, nil
+}
+
+// enforcement returns the decision record for an action that escalated or
+// lifted the account's level, or nil if the account was not changed. It is
+// logged in the same format as the worker's decision records, so the
+// decision history and revert see the account's current level.
+func enforcement(action *Action, account *structs.AdminAccount, outcome string) *structs.Decision {
+	if account == nil {
+		return nil
+	}
+	record := &structs.Decision{
+		Account:  *account,
+		Rules:    []structs.RuleOutcome{},
+		Action:   "act",
+		Rule:     action.Rule,
+		Policy:   action.Policy,
+		Enforced: true,
+	}
+	switch outcome {
+	case OutcomeEscalated:
+		record.Level = action.FollowUpLevel
+		record.Reason = "follow-up escalated " + action.Level + " to " + action.FollowUpLevel
+	case OutcomeLifted:
+		record.Level = "none"
+		record.Reason = "follow-up lifted " + action.Level
+	default:
+		return nil
+	}
+	return record
+}
+
+// HasLevel reports whether the account still has the suspend level applied.
+func HasLevel(account *structs.AdminAccount, level string) bool {
+	switch level {
+	case "sensitive":
+		return account.Sensitized
+	case "disable":
+		return account.Disabled
+	case "silence":
+		return account.Silenced
+	case "suspend":
+		return account.Suspended
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package followup
+
+import (
+	"time"
+)
+
+// Action is a follow-up action scheduled against an account that
+// mastoban acted on, e.g. to escalate a silence to a suspension,
+// or to lift a sensitive flag after a probation period.
+type Action struct {
+	// ID identifies the pending action in the store.
+	ID string `json:"id"`
+
+	// AccountID and Username identify the account.
+	AccountID string `json:"account_id"`
+	Username  string `json:"username"`
+
+	// Level is the suspend level mastoban applied. The follow-up only
+	// runs while the account still has it; if a moderator cleared or
+	// changed it, the follow-up is dropped.
+	Level string `json:"level"`
+
+	// FollowUpLevel is the suspend level to escalate to.
+	// It is empty when Lift is set.
+	FollowUpLevel string `json:"follow_up_level,omitempty"`
+
+	// Lift reverses Level instead of escalating.
+	Lift bool `json:"lift,omitempty"`
+
+	// Text is sent with an escalation.
+	Text string `json:"text,omitempty"`
+
+	// Rule and Policy identify what scheduled the follow-up.
+	Rule   string `json:"rule"`
+	Policy string `json:"policy,omitempty"`
+
+	// CreatedAt is when the follow-up was scheduled, and DueAt is when it runs.
+	CreatedAt time.Time `json:"created_at"`
+	DueAt     time.Time `json:"due_at"`
+}
+
+// Store keeps pending follow-up actions until they are due.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Put adds or replaces a pending action.
+	Put(action *Action) error
+
+	// Due returns the pending actions due at or before now, oldest first.
+	Due(now time.Time) ([]Action, error)
+
+	// ForAccount returns the pending actions for an account, oldest first.
+	ForAccount(accountID string) ([]Action, error)
+
+	// Delete removes a pending action.
+	Delete(id string) error
+}
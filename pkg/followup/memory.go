@@ -0,0 +1,75 @@
+package followup
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps pending actions in memory. They are lost when the
+// process exits, so use a persistent store unless the executor runs in
+// the same process, e.g. for testing.
+type MemoryStore struct {
+	mu      sync.Mutex
+	actions map[string]Action
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{actions: make(map[string]Action)}
+}
+
+// Put adds or replaces a pending action.
+func (s *MemoryStore) Put(action *Action) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.actions[action.ID] = *action
+	return nil
+}
+
+// Due returns the pending actions due at or before now, oldest first.
+func (s *MemoryStore) Due(now time.Time) ([]Action, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := []Action{}
+	for _, action := range s.actions {
+		if !action.DueAt.After(now) {
+			due = append(due, action)
+		}
+	}
+	sortDue(due)
+	return due, nil
+}
+
+// ForAccount returns the pending actions for an account, oldest first.
+func (s *MemoryStore) ForAccount(accountID string) ([]Action, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := []Action{}
+	for _, action := range s.actions {
+		if action.AccountID == accountID {
+			pending = append(pending, action)
+		}
+	}
+	sortDue(pending)
+	return pending, nil
+}
+
+// Delete removes a pending action.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.actions, id)
+	return nil
+}
+
+// sortDue sorts actions by when they are due, oldest first.
+func sortDue(actions []Action) {
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].DueAt.Before(actions[j].DueAt)
+	})
+}
@@ -19,20 +19,43 @@ type Record struct {
 	} `maxminddb:"country"`
 }
 
+// ASNRecord defines the fields to fetch from the GeoIP ASN database.
+type ASNRecord struct {
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
 // GeoIPData represents the data returned.
 type GeoIPData struct {
 	IP        net.IP `json:"ip"`
 	Continent string `json:"continent_code"`
 	Country   string `json:"country_code"`
+
+	// ASN and ASOrganization are only set when an ASN database is configured.
+	ASN            uint   `json:"autonomous_system_number,omitempty"`
+	ASOrganization string `json:"autonomous_system_organization,omitempty"`
 }
 
+// Option for the GeoIP instance
+type Option func(geoIP *GeoIP)
+
 // GeoIP struct is used to configure and exec geoip functions
 type GeoIP struct {
-	db *maxminddb.Reader
+	db      *maxminddb.Reader
+	asnDB   *maxminddb.Reader
+	asnFile string
+}
+
+// WithASNDatabase sets the path to a GeoLite2-ASN database file.
+// When set, lookups also return the autonomous system number and organization.
+func WithASNDatabase(asnfile string) Option {
+	return func(geoIP *GeoIP) {
+		geoIP.asnFile = asnfile
+	}
 }
 
 // New sets up and configures a new GeoIP struct for use
-func New(dbfile *string) (*GeoIP, error) {
+func New(dbfile *string, opts ...Option) (*GeoIP, error) {
 	// If the database file is not specified, use the default.
 	dbfqpn := ""
 
@@ -45,12 +68,34 @@ func New(dbfile *string) (*GeoIP, error) {
 		}
 	}
 
+	geoIP := &GeoIP{}
+
+	// apply the list of options to GeoIP
+	for _, opt := range opts {
+		opt(geoIP)
+	}
+
 	// Connect to the local MaxMind GeoIP database file
 	if db, err := maxminddb.Open(dbfqpn); err != nil {
 		return nil, errors.New("maxminddb.Open returned an error opening the database file '" + dbfqpn + "': " + err.Error())
 	} else {
-		return &GeoIP{db: db}, nil
+		geoIP.db = db
 	}
+
+	// Connect to the optional MaxMind GeoIP ASN database file
+	if geoIP.asnFile != "" {
+		asnfqpn, err := filepath.Abs(geoIP.asnFile)
+		if err != nil {
+			return nil, errors.New("filepath.Abs returned an error parsing ASN database file path '" + geoIP.asnFile + "': " + err.Error())
+		}
+		if asnDB, err := maxminddb.Open(asnfqpn); err != nil {
+			return nil, errors.New("maxminddb.Open returned an error opening the ASN database file '" + asnfqpn + "': " + err.Error())
+		} else {
+			geoIP.asnDB = asnDB
+		}
+	}
+
+	return geoIP, nil
 }
 
 // Lookip GeoIP data for the given IP address.
@@ -63,9 +108,21 @@ func (geoIP *GeoIP) Lookup(ip net.IP) (*GeoIPData, error) {
 	}
 
 	// Return the IP addr, continent, and country data
-	return &GeoIPData{
+	ipData := &GeoIPData{
 		IP:        ip,
 		Continent: data.Continent.Code,
 		Country:   data.Country.IsoCode,
-	}, nil
+	}
+
+	// Add the ASN data if the ASN database is configured
+	if geoIP.asnDB != nil {
+		var asn ASNRecord = ASNRecord{}
+		if err := geoIP.asnDB.Lookup(ip, &asn); err != nil {
+			return nil, errors.New("geoIP.asnDB.Lookup returned an error looking up the IP address: " + err.Error())
+		}
+		ipData.ASN = asn.AutonomousSystemNumber
+		ipData.ASOrganization = asn.AutonomousSystemOrganization
+	}
+
+	return ipData, nil
 }
@@ -0,0 +1,221 @@
+package mastoclient
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rmrfslashbin/mastoban/pkg/structs"
+)
+
+// AccountFilter limits the accounts returned by ListAccounts.
+// Empty fields are not filtered on.
+// See https://docs.joinmastodon.org/methods/admin/accounts/#v2
+type AccountFilter struct {
+	// Origin is "local" or "remote".
+	Origin string
+
+	// Status is "active", "pending", "disabled", "silenced" or "suspended".
+	Status string
+
+	// Permissions is "staff" to list only accounts with staff permissions.
+	Permissions string
+
+	// RoleIDs lists only accounts with one of the given roles.
+	RoleIDs []string
+
+	// InvitedBy lists only accounts invited by the given account ID.
+	InvitedBy string
+
+	// Username, DisplayName, ByDomain, Email and IP filter on the
+	// account details. IP may be a single address or a CIDR range.
+	Username    string
+	DisplayName string
+	ByDomain    string
+	Email       string
+	IP          string
+
+	// Limit is the number of accounts fetched per page. Mastodon
+	// defaults to 100, which is also the maximum.
+	Limit int
+}
+
+// values returns the filter as query parameters.
+func (f *AccountFilter) values() url.Values {
+	v := url.Values{}
+	if f == nil {
+		return v
+	}
+	set := func(key, value string) {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	set("origin", f.Origin)
+	set("status", f.Status)
+	set("permissions", f.Permissions)
+	set("invited_by", f.InvitedBy)
+	set("username", f.Username)
+	set("display_name", f.DisplayName)
+	set("by_domain", f.ByDomain)
+	set("email", f.Email)
+	set("ip", f.IP)
+	for _, id := range f.RoleIDs {
+		v.Add("role_ids[]", id)
+	}
+	if f.Limit > 0 {
+		v.Set("limit", strconv.Itoa(f.Limit))
+	}
+	return v
+}
+
+// GetAccount fetches the current state of a Mastodon account.
+// The v2 API has no single account endpoint, so the v1 endpoint is used.
+func (c *Config) GetAccount(id string) (*structs.AdminAccount, error) {
+	body, _, err := c.get(c.instance + "/api/v1/admin/accounts/" + url.PathEscape(id))
+	if err != nil {
+		return nil, err
+	}
+
+	account := &structs.AdminAccount{}
+	if err := json.Unmarshal(body, account); err != nil {
+		return nil, &GetFailed{Msg: "unable to parse account " + id, Err: err}
+	}
+	return account, nil
+}
+
+// AccountIterator pages through the accounts matching a filter.
+// Use it like bufio.Scanner:
+//
+//	accounts := client.ListAccounts(filter)
+//	for accounts.Next() {
+//		account := accounts.Account()
+//	}
+//	if err := accounts.Err(); err != nil {
+//		...
+//	}
+type AccountIterator struct {
+	c       *Config
+	next    string
+	page    []structs.AdminAccount
+	account *structs.AdminAccount
+	err     error
+}
+
+// ListAccounts returns an iterator over the accounts matching the filter.
+// Pages are fetched as needed by following the Link header.
+func (c *Config) ListAccounts(filter *AccountFilter) *AccountIterator {
+	endpoint := c.instance + "/api/v2/admin/accounts"
+	if query := filter.values().Encode(); query != "" {
+		endpoint += "?" + query
+	}
+	return &AccountIterator{c: c, next: endpoint}
+}
+
+// Next advances to the next account, fetching the next page if needed.
+// It returns false when there are no more accounts or an error occurred.
+func (it *AccountIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || it.next == "" {
+			it.account = nil
+			return false
+		}
+
+		body, link, err := it.c.get(it.next)
+		if err != nil {
+			it.err = err
+			continue
+		}
+		page := []structs.AdminAccount{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			it.err = &GetFailed{Msg: "unable to parse accounts page", Err: err}
+			continue
+		}
+		it.page = page
+		it.next = nextLink(link)
+	}
+
+	it.account = &it.page[0]
+	it.page = it.page[1:]
+	return true
+}
+
+// Account returns the current account.
+func (it *AccountIterator) Account() *structs.AdminAccount {
+	return it.account
+}
+
+// Err returns the first error encountered while fetching accounts.
+func (it *AccountIterator) Err() error {
+	return it.err
+}
+
+// nextLink returns the rel="next" URL from a Link header, if any.
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		target := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if strings.ReplaceAll(strings.TrimSpace(param), `"`, "") == "rel=next" {
+				return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+			}
+		}
+	}
+	return ""
+}
+
+// get sends an authenticated GET request and returns the
+// response body and Link header.
+func (c *Config) get(endpoint string) ([]byte, string, error) {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Set the required headers
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	//send request and get the response
+	client := &http.Client{}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, "", &GetFailed{Msg: "unable to fetch " + endpoint, Err: err}
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", &GetFailed{Status: res.Status, Msg: "unable to read response from " + endpoint, Err: err}
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, "", &GetFailed{
+			Status: res.Status,
+			Msg:    "unable to fetch " + endpoint,
+			Err:    errors.New(string(body)),
+		}
+	}
+	return body, res.Header.Get("Link"), nil
+}
+
+// NotFound reports whether err is a request for a record Mastodon doesn't have,
+// e.g. an account or block that was deleted.
+func NotFound(err error) bool {
+	var getFailed *GetFailed
+	if errors.As(err, &getFailed) {
+		return strings.HasPrefix(getFailed.Status, "404")
+	}
+	var deleteFailed *DeleteFailed
+	return errors.As(err, &deleteFailed) && strings.HasPrefix(deleteFailed.Status, "404")
+}
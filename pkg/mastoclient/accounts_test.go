@@ -0,0 +1,167 @@
+package mastoclient
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{
+			name:   "next and prev",
+			header: `<https://example.com/api/v2/admin/accounts?max_id=7>; rel="next", <https://example.com/api/v2/admin/accounts?min_id=9>; rel="prev"`,
+			want:   "https://example.com/api/v2/admin/accounts?max_id=7",
+		},
+		{
+			name:   "prev first",
+			header: `<https://example.com/?min_id=9>; rel="prev", <https://example.com/?max_id=7>; rel="next"`,
+			want:   "https://example.com/?max_id=7",
+		},
+		{
+			name:   "unquoted rel",
+			header: `<https://example.com/?max_id=7>; rel=next`,
+			want:   "https://example.com/?max_id=7",
+		},
+		{
+			name:   "no next",
+			header: `<https://example.com/?min_id=9>; rel="prev"`,
+		},
+		{
+			name: "empty",
+		},
+		{
+			name:   "missing angle brackets",
+			header: `https://example.com/?max_id=7; rel="next"`,
+		},
+		{
+			name:   "missing rel",
+			header: `<https://example.com/?max_id=7>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextLink(tt.header); got != tt.want {
+				t.Errorf("nextLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccountIterator(t *testing.T) {
+	tests := []struct {
+		name string
+		// pages maps the page query parameter to the page body and
+		// Link header, in which {url} is replaced by the server URL.
+		pages    map[string][2]string
+		status   map[string]int
+		accounts []string
+		err      bool
+	}{
+		{
+			name: "several pages",
+			pages: map[string][2]string{
+				"":  {`[{"id":"1"},{"id":"2"}]`, `<{url}/api/v2/admin/accounts?page=2>; rel="next"`},
+				"2": {`[{"id":"3"}]`, `<{url}/api/v2/admin/accounts?page=1>; rel="prev", <{url}/api/v2/admin/accounts?page=3>; rel="next"`},
+				"3": {`[{"id":"4"}]`, `<{url}/api/v2/admin/accounts?page=2>; rel="prev"`},
+			},
+			accounts: []string{"1", "2", "3", "4"},
+		},
+		{
+			name: "empty page",
+			pages: map[string][2]string{
+				"":  {`[{"id":"1"}]`, `<{url}/api/v2/admin/accounts?page=2>; rel="next"`},
+				"2": {`[]`, `<{url}/api/v2/admin/accounts?page=3>; rel="next"`},
+				"3": {`[{"id":"2"}]`, ``},
+			},
+			accounts: []string{"1", "2"},
+		},
+		{
+			name: "no link header",
+			pages: map[string][2]string{
+				"": {`[{"id":"1"},{"id":"2"}]`, ``},
+			},
+			accounts: []string{"1", "2"},
+		},
+		{
+			name: "malformed link header",
+			pages: map[string][2]string{
+				"":  {`[{"id":"1"}]`, `{url}/api/v2/admin/accounts?page=2; rel="next"`},
+				"2": {`[{"id":"2"}]`, ``},
+			},
+			accounts: []string{"1"},
+		},
+		{
+			name: "error on a later page",
+			pages: map[string][2]string{
+				"":  {`[{"id":"1"}]`, `<{url}/api/v2/admin/accounts?page=2>; rel="next"`},
+				"2": {`{"error":"This action is not allowed"}`, ``},
+			},
+			status:   map[string]int{"2": http.StatusForbidden},
+			accounts: []string{"1"},
+			err:      true,
+		},
+		{
+			name: "error on the first page",
+			pages: map[string][2]string{
+				"": {`{"error":"The access token is invalid"}`, ``},
+			},
+			status: map[string]int{"": http.StatusUnauthorized},
+			err:    true,
+		},
+		{
+			name: "malformed page",
+			pages: map[string][2]string{
+				"": {`{"id":"1"}`, ``},
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var url string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				page := r.URL.Query().Get("page")
+				p, ok := tt.pages[page]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				if r.URL.Query().Get("status") != "active" && page == "" {
+					t.Errorf("first page query = %q, want the filter", r.URL.RawQuery)
+				}
+				if p[1] != "" {
+					w.Header().Set("Link", strings.ReplaceAll(p[1], "{url}", url))
+				}
+				if status, ok := tt.status[page]; ok {
+					w.WriteHeader(status)
+				}
+				fmt.Fprint(w, p[0])
+			})
+			url = c.instance
+
+			var accounts []string
+			it := c.ListAccounts(&AccountFilter{Status: "active"})
+			for it.Next() {
+				accounts = append(accounts, it.Account().Id)
+			}
+			if !reflect.DeepEqual(accounts, tt.accounts) {
+				t.Errorf("accounts = %v, want %v", accounts, tt.accounts)
+			}
+			if err := it.Err(); (err != nil) != tt.err {
+				t.Errorf("Err() = %v, want error %v", err, tt.err)
+			}
+			if it.Next() {
+				t.Error("Next() = true after the last account")
+			}
+		})
+	}
+}
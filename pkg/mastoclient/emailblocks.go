@@ -0,0 +1,149 @@
+package mastoclient
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// EmailDomainBlock is a Mastodon email domain block. Mastodon also
+// blocks the subdomains of a blocked domain.
+// See https://docs.joinmastodon.org/entities/Admin_EmailDomainBlock/
+type EmailDomainBlock struct {
+	ID        string    `json:"id"`
+	Domain    string    `json:"domain"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CanonicalEmailBlock is a Mastodon canonical email block. Mastodon
+// blocks the normalized address, e.g. ignoring dots and +suffixes.
+// See https://docs.joinmastodon.org/entities/Admin_CanonicalEmailBlock/
+type CanonicalEmailBlock struct {
+	ID                 string `json:"id"`
+	CanonicalEmailHash string `json:"canonical_email_hash"`
+}
+
+// ListEmailDomainBlocks fetches every email domain block on the instance,
+// following the Link header.
+func (c *Config) ListEmailDomainBlocks() ([]EmailDomainBlock, error) {
+	blocks := []EmailDomainBlock{}
+	next := c.instance + "/api/v1/admin/email_domain_blocks?limit=200"
+	for next != "" {
+		body, link, err := c.get(next)
+		if err != nil {
+			return nil, err
+		}
+		page := []EmailDomainBlock{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, &GetFailed{Msg: "unable to parse email domain blocks page", Err: err}
+		}
+		blocks = append(blocks, page...)
+		next = nextLink(link)
+	}
+	return blocks, nil
+}
+
+// CreateEmailDomainBlock blocks signups from an email domain.
+func (c *Config) CreateEmailDomainBlock(domain string) (*EmailDomainBlock, error) {
+	data := url.Values{}
+	data.Set("domain", domain)
+
+	body, err := c.postForm(c.instance+"/api/v1/admin/email_domain_blocks", data, "Failed to create email domain block "+domain)
+	if err != nil {
+		return nil, err
+	}
+
+	block := &EmailDomainBlock{}
+	if err := json.Unmarshal(body, block); err != nil {
+		return nil, &PostFailed{Msg: "unable to parse email domain block " + domain, Err: err}
+	}
+	return block, nil
+}
+
+// EnsureEmailDomainBlock blocks an email domain unless it, or a parent
+// domain, is already blocked. It returns the block that covers the
+// domain and whether it was created.
+func (c *Config) EnsureEmailDomainBlock(domain string) (*EmailDomainBlock, bool, error) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	blocks, err := c.ListEmailDomainBlocks()
+	if err != nil {
+		return nil, false, err
+	}
+	for i := range blocks {
+		blocked := strings.ToLower(blocks[i].Domain)
+		if domain == blocked || strings.HasSuffix(domain, "."+blocked) {
+			return &blocks[i], false, nil
+		}
+	}
+
+	block, err := c.CreateEmailDomainBlock(domain)
+	if err != nil {
+		return nil, false, err
+	}
+	return block, true, nil
+}
+
+// DeleteEmailDomainBlock deletes the email domain block with the given ID.
+func (c *Config) DeleteEmailDomainBlock(id string) error {
+	return c.delete(c.instance+"/api/v1/admin/email_domain_blocks/"+url.PathEscape(id), "Failed to delete email domain block "+id)
+}
+
+// TestCanonicalEmailBlocks returns the canonical email blocks that match an email address.
+func (c *Config) TestCanonicalEmailBlocks(email string) ([]CanonicalEmailBlock, error) {
+	data := url.Values{}
+	data.Set("email", email)
+
+	body, err := c.postForm(c.instance+"/api/v1/admin/canonical_email_blocks/test", data, "Failed to test canonical email blocks")
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := []CanonicalEmailBlock{}
+	if err := json.Unmarshal(body, &blocks); err != nil {
+		return nil, &PostFailed{Msg: "unable to parse canonical email blocks", Err: err}
+	}
+	return blocks, nil
+}
+
+// CreateCanonicalEmailBlock blocks signups from an email address, in any of its forms.
+func (c *Config) CreateCanonicalEmailBlock(email string) (*CanonicalEmailBlock, error) {
+	data := url.Values{}
+	data.Set("email", email)
+
+	body, err := c.postForm(c.instance+"/api/v1/admin/canonical_email_blocks", data, "Failed to create canonical email block")
+	if err != nil {
+		return nil, err
+	}
+
+	block := &CanonicalEmailBlock{}
+	if err := json.Unmarshal(body, block); err != nil {
+		return nil, &PostFailed{Msg: "unable to parse canonical email block", Err: err}
+	}
+	return block, nil
+}
+
+// EnsureCanonicalEmailBlock blocks an email address unless it is already
+// blocked. It returns the block that covers the address and whether it
+// was created.
+func (c *Config) EnsureCanonicalEmailBlock(email string) (*CanonicalEmailBlock, bool, error) {
+	blocks, err := c.TestCanonicalEmailBlocks(email)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(blocks) > 0 {
+		return &blocks[0], false, nil
+	}
+
+	block, err := c.CreateCanonicalEmailBlock(email)
+	if err != nil {
+		return nil, false, err
+	}
+	return block, true, nil
+}
+
+// DeleteCanonicalEmailBlock deletes the canonical email block with the given ID.
+func (c *Config) DeleteCanonicalEmailBlock(id string) error {
+	return c.delete(c.instance+"/api/v1/admin/canonical_email_blocks/"+url.PathEscape(id), "Failed to delete canonical email block "+id)
+}
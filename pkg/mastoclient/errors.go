@@ -1,5 +1,77 @@
 package mastoclient
 
+// DeleteFailed is returned when an HTTP DELETE operation to Mastodon fails
+type DeleteFailed struct {
+	// Err is a proper error object
+	Err error
+
+	// Msg is a string to give addition context to the error message
+	Msg string
+
+	// Status is used to convey http status codes or other related data
+	Status string
+}
+
+// Error returns the error message
+func (e *DeleteFailed) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = "delete failed"
+	}
+	if e.Status != "" {
+		msg += " (" + e.Status + ")"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// GetFailed is returned when an HTTP GET operation to Mastodon fails
+type GetFailed struct {
+	// Err is a proper error object
+	Err error
+
+	// Msg is a string to give addition context to the error message
+	Msg string
+
+	// Status is used to convey http status codes or other related data
+	Status string
+}
+
+// Error returns the error message
+func (e *GetFailed) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = "get failed"
+	}
+	if e.Status != "" {
+		msg += " (" + e.Status + ")"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// InvalidIPBlock is returned when an IP block can't be created as described
+type InvalidIPBlock struct {
+	Err error
+	Msg string
+}
+
+// Error returns the error message
+func (e *InvalidIPBlock) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = "invalid ip block"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
 // InvalidSuspendType is returned when the provided suspend type is invalid
 type InvalidSuspendType struct {
 	Err          error
@@ -9,16 +81,17 @@ type InvalidSuspendType struct {
 
 // Error returns the error message
 func (e *InvalidSuspendType) Error() string {
-	if e.Msg == "" {
-		e.Msg = "invalid suspend type"
+	msg := e.Msg
+	if msg == "" {
+		msg = "invalid suspend type"
 	}
 	if e.typeProvided != nil {
-		e.Msg += ": " + *e.typeProvided
+		msg += ": " + *e.typeProvided
 	}
 	if e.Err != nil {
-		e.Msg += ": " + e.Err.Error()
+		msg += ": " + e.Err.Error()
 	}
-	return e.Msg
+	return msg
 }
 
 // NoAccessToken is returned when the Mastodon access token value is missing.
@@ -29,13 +102,14 @@ type NoAccessToken struct {
 
 // Error returns the error message
 func (e *NoAccessToken) Error() string {
-	if e.Msg == "" {
-		e.Msg = "No access token. use WithAccessToken()"
+	msg := e.Msg
+	if msg == "" {
+		msg = "No access token. use WithAccessToken()"
 	}
 	if e.Err != nil {
-		e.Msg += ": " + e.Err.Error()
+		msg += ": " + e.Err.Error()
 	}
-	return e.Msg
+	return msg
 }
 
 // NoInstance is returned when the Mastodon instance value is missing.
@@ -46,13 +120,14 @@ type NoInstance struct {
 
 // Error returns the error message
 func (e *NoInstance) Error() string {
-	if e.Msg == "" {
-		e.Msg = "no instance. use WithInstance()"
+	msg := e.Msg
+	if msg == "" {
+		msg = "no instance. use WithInstance()"
 	}
 	if e.Err != nil {
-		e.Msg += ": " + e.Err.Error()
+		msg += ": " + e.Err.Error()
 	}
-	return e.Msg
+	return msg
 }
 
 // PostFailed is retrned when an HTTP POST operation to Mastodon fails
@@ -69,11 +144,12 @@ type PostFailed struct {
 
 // Error returns the error message
 func (e *PostFailed) Error() string {
-	if e.Msg == "" {
-		e.Msg = "post failed"
+	msg := e.Msg
+	if msg == "" {
+		msg = "post failed"
 	}
 	if e.Err != nil {
-		e.Msg += ": " + e.Err.Error()
+		msg += ": " + e.Err.Error()
 	}
-	return e.Msg
+	return msg
 }
@@ -0,0 +1,33 @@
+package mastoclient
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorIsRepeatable(t *testing.T) {
+	level := "ban"
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"delete failed", &DeleteFailed{Status: "404 Not Found", Msg: "delete", Err: errors.New("body")}, "delete (404 Not Found): body"},
+		{"get failed", &GetFailed{Status: "500 Internal Server Error", Err: errors.New("body")}, "get failed (500 Internal Server Error): body"},
+		{"invalid ip block", &InvalidIPBlock{Msg: "invalid ip 'x'"}, "invalid ip 'x'"},
+		{"invalid suspend type", &InvalidSuspendType{typeProvided: &level}, "invalid suspend type: ban"},
+		{"no access token", &NoAccessToken{}, "No access token. use WithAccessToken()"},
+		{"no instance", &NoInstance{Err: errors.New("empty")}, "no instance. use WithInstance(): empty"},
+		{"post failed", &PostFailed{Msg: "suspend", Err: errors.New("body")}, "suspend: body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				if got := tt.err.Error(); got != tt.want {
+					t.Errorf("Error() call %d = %q, want %q", i+1, got, tt.want)
+				}
+			}
+		})
+	}
+}
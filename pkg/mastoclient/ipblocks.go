@@ -0,0 +1,181 @@
+package mastoclient
+
+import (
+	"encoding/json"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IPBlock is a Mastodon IP block.
+// See https://docs.joinmastodon.org/entities/Admin_IpBlock/
+type IPBlock struct {
+	ID        string     `json:"id"`
+	IP        string     `json:"ip"`
+	Severity  string     `json:"severity"`
+	Comment   string     `json:"comment"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Network returns the blocked network. Single addresses are returned as
+// a /32 or /128 network. It returns nil if the IP can't be parsed.
+func (b *IPBlock) Network() *net.IPNet {
+	return parseNetwork(b.IP)
+}
+
+// IPBlockInput describes an IP block to create.
+type IPBlockInput struct {
+	// IP is an IP address or CIDR network.
+	IP string
+
+	// Severity is sign_up_requires_approval, sign_up_block or no_access.
+	Severity string
+
+	// Comment is shown to moderators alongside the block.
+	Comment string
+
+	// ExpiresIn is how long the block lasts. Zero never expires.
+	ExpiresIn time.Duration
+}
+
+// ValidIPBlockSeverity reports whether severity is accepted by CreateIPBlock.
+// Valid severities as defined by https://docs.joinmastodon.org/methods/admin/ip_blocks/#create
+func ValidIPBlockSeverity(severity string) bool {
+	switch severity {
+	case "sign_up_requires_approval", "sign_up_block", "no_access":
+		return true
+	}
+	return false
+}
+
+// ListIPBlocks fetches every IP block on the instance, following the Link header.
+func (c *Config) ListIPBlocks() ([]IPBlock, error) {
+	blocks := []IPBlock{}
+	next := c.instance + "/api/v1/admin/ip_blocks?limit=200"
+	for next != "" {
+		body, link, err := c.get(next)
+		if err != nil {
+			return nil, err
+		}
+		page := []IPBlock{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, &GetFailed{Msg: "unable to parse ip blocks page", Err: err}
+		}
+		blocks = append(blocks, page...)
+		next = nextLink(link)
+	}
+	return blocks, nil
+}
+
+// CreateIPBlock creates an IP block.
+func (c *Config) CreateIPBlock(in *IPBlockInput) (*IPBlock, error) {
+	if !ValidIPBlockSeverity(in.Severity) {
+		return nil, &InvalidIPBlock{Msg: "invalid severity '" + in.Severity + "'"}
+	}
+	if parseNetwork(in.IP) == nil {
+		return nil, &InvalidIPBlock{Msg: "invalid ip '" + in.IP + "'"}
+	}
+
+	data := url.Values{}
+	data.Set("ip", in.IP)
+	data.Set("severity", in.Severity)
+	if in.Comment != "" {
+		data.Set("comment", in.Comment)
+	}
+	if in.ExpiresIn > 0 {
+		data.Set("expires_in", strconv.FormatInt(int64(in.ExpiresIn/time.Second), 10))
+	}
+
+	body, err := c.postForm(c.instance+"/api/v1/admin/ip_blocks", data, "Failed to create ip block "+in.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	block := &IPBlock{}
+	if err := json.Unmarshal(body, block); err != nil {
+		return nil, &PostFailed{Msg: "unable to parse ip block " + in.IP, Err: err}
+	}
+	return block, nil
+}
+
+// DeleteIPBlock deletes the IP block with the given ID.
+func (c *Config) DeleteIPBlock(id string) error {
+	return c.delete(c.instance+"/api/v1/admin/ip_blocks/"+url.PathEscape(id), "Failed to delete ip block "+id)
+}
+
+// EnsureIPBlock creates an IP block unless an existing, unexpired block
+// at least as strict already covers the network. It returns the block that
+// covers the network and whether it was created.
+func (c *Config) EnsureIPBlock(in *IPBlockInput) (*IPBlock, bool, error) {
+	network := parseNetwork(in.IP)
+	if network == nil {
+		return nil, false, &InvalidIPBlock{Msg: "invalid ip '" + in.IP + "'"}
+	}
+
+	blocks, err := c.ListIPBlocks()
+	if err != nil {
+		return nil, false, err
+	}
+	now := time.Now()
+	for i := range blocks {
+		block := &blocks[i]
+		if block.ExpiresAt != nil && block.ExpiresAt.Before(now) {
+			continue
+		}
+		if severityRank(block.Severity) >= severityRank(in.Severity) && covers(block.Network(), network) {
+			return block, false, nil
+		}
+	}
+
+	block, err := c.CreateIPBlock(in)
+	if err != nil {
+		return nil, false, err
+	}
+	return block, true, nil
+}
+
+// severityRank orders IP block severities from least to most strict.
+// Unknown severities rank lowest.
+func severityRank(severity string) int {
+	switch severity {
+	case "sign_up_requires_approval":
+		return 1
+	case "sign_up_block":
+		return 2
+	case "no_access":
+		return 3
+	}
+	return 0
+}
+
+// parseNetwork parses an IP address or CIDR network.
+func parseNetwork(s string) *net.IPNet {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+	}
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil
+	}
+	return network
+}
+
+// covers reports whether outer contains all of inner.
+func covers(outer *net.IPNet, inner *net.IPNet) bool {
+	if outer == nil || inner == nil {
+		return false
+	}
+	outerOnes, outerBits := outer.Mask.Size()
+	innerOnes, innerBits := inner.Mask.Size()
+	return outerBits == innerBits && outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
@@ -0,0 +1,80 @@
+package mastoclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestEnsureIPBlock(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name     string
+		existing []IPBlock
+		in       IPBlockInput
+		created  bool
+	}{
+		{
+			name:    "no blocks",
+			in:      IPBlockInput{IP: "192.0.2.1", Severity: "sign_up_block"},
+			created: true,
+		},
+		{
+			name:     "same severity covers",
+			existing: []IPBlock{{ID: "1", IP: "192.0.2.0/24", Severity: "sign_up_block"}},
+			in:       IPBlockInput{IP: "192.0.2.1", Severity: "sign_up_block"},
+		},
+		{
+			name:     "stricter severity covers",
+			existing: []IPBlock{{ID: "1", IP: "192.0.2.0/24", Severity: "no_access"}},
+			in:       IPBlockInput{IP: "192.0.2.1", Severity: "sign_up_block"},
+		},
+		{
+			name:     "weaker severity",
+			existing: []IPBlock{{ID: "1", IP: "192.0.2.0/24", Severity: "sign_up_requires_approval"}},
+			in:       IPBlockInput{IP: "192.0.2.1", Severity: "sign_up_block"},
+			created:  true,
+		},
+		{
+			name:     "expired",
+			existing: []IPBlock{{ID: "1", IP: "192.0.2.0/24", Severity: "no_access", ExpiresAt: &expired}},
+			in:       IPBlockInput{IP: "192.0.2.1", Severity: "sign_up_block"},
+			created:  true,
+		},
+		{
+			name:     "narrower network",
+			existing: []IPBlock{{ID: "1", IP: "192.0.2.1", Severity: "no_access"}},
+			in:       IPBlockInput{IP: "192.0.2.0/24", Severity: "sign_up_block"},
+			created:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodGet:
+					json.NewEncoder(w).Encode(tt.existing)
+				case http.MethodPost:
+					json.NewEncoder(w).Encode(IPBlock{ID: "new", IP: r.FormValue("ip"), Severity: r.FormValue("severity")})
+				}
+			})
+
+			block, created, err := c.EnsureIPBlock(&tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created != tt.created {
+				t.Errorf("created = %v, want %v", created, tt.created)
+			}
+			want := "1"
+			if tt.created {
+				want = "new"
+			}
+			if block.ID != want {
+				t.Errorf("block = %+v, want block %s", block, want)
+			}
+		})
+	}
+}
@@ -70,6 +70,16 @@ func WithLogger(log *zerolog.Logger) Option {
 	}
 }
 
+// ValidSuspendLevel reports whether level is a suspend level accepted by Suspend.
+// Valid suspend types as defined by https://docs.joinmastodon.org/methods/admin/accounts/#form-data-parameters
+func ValidSuspendLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "none", "sensitive", "disable", "silence", "suspend":
+		return true
+	}
+	return false
+}
+
 type SuspendInput struct {
 	ID           string
 	SuspendText  string
@@ -81,16 +91,8 @@ type SuspendInput struct {
 func (c *Config) Suspend(in *SuspendInput) error {
 	suspendLevel := strings.ToLower(in.SuspendLevel)
 
-	// Valid suspend types as defined by https://docs.joinmastodon.org/methods/admin/accounts/#form-data-parameters
-	validTypes := make(map[string]struct{})
-	validTypes["none"] = struct{}{}
-	validTypes["sensitive"] = struct{}{}
-	validTypes["disable"] = struct{}{}
-	validTypes["silence"] = struct{}{}
-	validTypes["suspend"] = struct{}{}
-
 	// Check to ensure the suspend level is valid
-	if _, ok := validTypes[suspendLevel]; !ok {
+	if !ValidSuspendLevel(suspendLevel) {
 		return &InvalidSuspendType{typeProvided: &suspendLevel}
 	}
 
@@ -103,10 +105,79 @@ func (c *Config) Suspend(in *SuspendInput) error {
 	data.Set("text", in.SuspendText)
 	data.Set("send_email_notification", "true")
 
+	return c.post(endpoint, data, "Failed to suspend account "+in.ID)
+}
+
+// Approve approves a pending Mastodon account.
+func (c *Config) Approve(id string) error {
+	endpoint := c.instance + "/api/v1/admin/accounts/" + id + "/approve"
+	return c.post(endpoint, url.Values{}, "Failed to approve account "+id)
+}
+
+// Reject rejects a pending Mastodon account. Mastodon deletes the
+// account, so it can only be used on accounts that are not approved.
+func (c *Config) Reject(id string) error {
+	endpoint := c.instance + "/api/v1/admin/accounts/" + id + "/reject"
+	return c.post(endpoint, url.Values{}, "Failed to reject account "+id)
+}
+
+// Unsuspend lifts the suspension of a Mastodon account.
+func (c *Config) Unsuspend(id string) error {
+	endpoint := c.instance + "/api/v1/admin/accounts/" + id + "/unsuspend"
+	return c.post(endpoint, url.Values{}, "Failed to unsuspend account "+id)
+}
+
+// Unsilence lifts the silence on a Mastodon account.
+func (c *Config) Unsilence(id string) error {
+	endpoint := c.instance + "/api/v1/admin/accounts/" + id + "/unsilence"
+	return c.post(endpoint, url.Values{}, "Failed to unsilence account "+id)
+}
+
+// Unsensitive stops marking a Mastodon account's media as sensitive.
+func (c *Config) Unsensitive(id string) error {
+	endpoint := c.instance + "/api/v1/admin/accounts/" + id + "/unsensitive"
+	return c.post(endpoint, url.Values{}, "Failed to unsensitive account "+id)
+}
+
+// Enable re-enables login for a disabled Mastodon account.
+func (c *Config) Enable(id string) error {
+	endpoint := c.instance + "/api/v1/admin/accounts/" + id + "/enable"
+	return c.post(endpoint, url.Values{}, "Failed to enable account "+id)
+}
+
+// Revert reverses a suspend level applied by Suspend.
+// The "none" level has nothing to reverse.
+func (c *Config) Revert(id string, suspendLevel string) error {
+	suspendLevel = strings.ToLower(suspendLevel)
+
+	switch suspendLevel {
+	case "none":
+		return nil
+	case "sensitive":
+		return c.Unsensitive(id)
+	case "disable":
+		return c.Enable(id)
+	case "silence":
+		return c.Unsilence(id)
+	case "suspend":
+		return c.Unsuspend(id)
+	}
+	return &InvalidSuspendType{typeProvided: &suspendLevel}
+}
+
+// post sends an authenticated, form encoded POST request. On failure,
+// the error carries msg and the response body.
+func (c *Config) post(endpoint string, data url.Values, msg string) error {
+	_, err := c.postForm(endpoint, data, msg)
+	return err
+}
+
+// postForm is post, returning the response body on success.
+func (c *Config) postForm(endpoint string, data url.Values, msg string) ([]byte, error) {
 	//create new POST request to the url and encoded form Data
 	req, err := http.NewRequest("POST", endpoint, strings.NewReader(data.Encode())) // URL-encoded payload
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Set the required headers
@@ -117,7 +188,7 @@ func (c *Config) Suspend(in *SuspendInput) error {
 	client := &http.Client{}
 	res, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer res.Body.Close()
 
@@ -126,13 +197,46 @@ func (c *Config) Suspend(in *SuspendInput) error {
 
 	// On success, just return
 	if res.StatusCode == 200 {
-		return nil
+		return body, nil
 	} else {
 		// Otherwise, return an error message with the body/failure text
-		return &PostFailed{
+		return nil, &PostFailed{
+			Status: res.Status,
+			Msg:    msg,
+			Err:    errors.New(string(body)),
+		}
+	}
+}
+
+// delete sends an authenticated DELETE request. On failure, the error
+// carries msg and the response body.
+func (c *Config) delete(endpoint string, msg string) error {
+	req, err := http.NewRequest("DELETE", endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	// Set the required headers
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	//send request and get the response
+	client := &http.Client{}
+	res, err := client.Do(req)
+	if err != nil {
+		return &DeleteFailed{Msg: msg, Err: err}
+	}
+	defer res.Body.Close()
+
+	// body will contain failure condition text
+	body, _ := io.ReadAll(res.Body)
+
+	if res.StatusCode != http.StatusOK {
+		return &DeleteFailed{
 			Status: res.Status,
-			Msg:    "Failed to suspend account " + in.ID,
+			Msg:    msg,
 			Err:    errors.New(string(body)),
 		}
 	}
+	return nil
 }
@@ -0,0 +1,20 @@
+package mastoclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestClient returns a client for a test server running handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Config {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := New(WithInstance(server.URL), WithAccessToken("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
@@ -0,0 +1,35 @@
+package policy
+
+// applyApproval adjusts the decision for the account's approval state.
+// Accounts that are already approved can't be approved or rejected, so
+// rejecting one acts on it instead, and approving one allows it. With
+// Policy.Approval set, pending accounts that are allowed are approved
+// and pending accounts that are acted on are rejected.
+func (e *Engine) applyApproval(d *Decision) *Decision {
+	if d.Input == nil || d.Input.Event == nil {
+		return d
+	}
+	if d.WouldAct != nil {
+		e.applyApproval(d.WouldAct)
+	}
+
+	if d.Input.Event.Object.Approved {
+		switch d.Action {
+		case ActionApprove:
+			d.Action = ActionAllow
+		case ActionReject:
+			d.Action = ActionAct
+		}
+		return d
+	}
+
+	if e.approval {
+		switch d.Action {
+		case ActionAllow:
+			d.Action = ActionApprove
+		case ActionAct:
+			d.Action = ActionReject
+		}
+	}
+	return d
+}
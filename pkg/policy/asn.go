@@ -0,0 +1,102 @@
+package policy
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ASNParams configures an ASNRule. Entries are autonomous system numbers,
+// with or without an "AS" prefix (e.g. "AS14061" or "14061").
+// An ASN on both lists is denied.
+//
+// ASN rules need the GeoLite2-ASN database; accounts without ASN data are skipped.
+type ASNParams struct {
+	// Allow lists ASNs to allow, skipping the remaining rules.
+	Allow []string `json:"allow"`
+
+	// Deny lists ASNs to act on.
+	Deny []string `json:"deny"`
+
+	// AllowFile is a file of ASNs to allow, one per line.
+	AllowFile string `json:"allow_file"`
+
+	// DenyFile is a file of ASNs to act on, one per line.
+	DenyFile string `json:"deny_file"`
+}
+
+// ASNRule allows or acts on accounts by the autonomous system of their IP address.
+type ASNRule struct {
+	name  string
+	allow map[uint]struct{}
+	deny  map[uint]struct{}
+}
+
+// NewASNRule creates a new ASNRule.
+func NewASNRule(name string, params *ASNParams) (*ASNRule, error) {
+	r := &ASNRule{name: name}
+
+	var err error
+	if r.allow, err = asnSet(params.Allow, params.AllowFile); err != nil {
+		return nil, &InvalidRule{Name: name, Err: err}
+	}
+	if r.deny, err = asnSet(params.Deny, params.DenyFile); err != nil {
+		return nil, &InvalidRule{Name: name, Err: err}
+	}
+
+	if len(r.allow) == 0 && len(r.deny) == 0 {
+		return nil, &InvalidRule{Name: name, Msg: "no allow or deny ASNs"}
+	}
+	return r, nil
+}
+
+// Name returns the name of the rule.
+func (r *ASNRule) Name() string {
+	return r.name
+}
+
+// Evaluate allows or acts on accounts whose IP address is in a listed ASN.
+func (r *ASNRule) Evaluate(in *Input) (*Result, error) {
+	asn := in.GeoIP.ASN
+	if asn == 0 {
+		return nil, nil
+	}
+
+	match := "AS" + strconv.FormatUint(uint64(asn), 10)
+	if _, ok := r.deny[asn]; ok {
+		return &Result{
+			Action: ActionAct,
+			Reason: match + " (" + in.GeoIP.ASOrganization + ") is in the deny list",
+			Match:  match,
+		}, nil
+	}
+	if _, ok := r.allow[asn]; ok {
+		return &Result{
+			Action: ActionAllow,
+			Reason: match + " (" + in.GeoIP.ASOrganization + ") is in the allow list",
+			Match:  match,
+		}, nil
+	}
+	return nil, nil
+}
+
+// asnSet parses a list of ASNs, and optionally a list file, into a lookup set.
+func asnSet(items []string, listFile string) (map[uint]struct{}, error) {
+	if listFile != "" {
+		fileItems, err := readListFile(listFile)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, fileItems...)
+	}
+
+	set := make(map[uint]struct{})
+	for _, item := range items {
+		item = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(item)), "AS")
+		asn, err := strconv.ParseUint(item, 10, 32)
+		if err != nil {
+			return nil, &InvalidPolicy{Msg: "invalid ASN '" + item + "'", Err: err}
+		}
+		set[uint(asn)] = struct{}{}
+	}
+	return set, nil
+}
@@ -0,0 +1,100 @@
+package policy
+
+// CIDRParams configures a CIDRRule. Entries are IPv4 or IPv6 CIDR
+// prefixes, or bare IP addresses.
+//
+// The longest matching prefix wins. When the same prefix is both
+// allowed and denied, it is denied.
+type CIDRParams struct {
+	// Allow lists prefixes to allow, skipping the remaining rules.
+	Allow []string `json:"allow"`
+
+	// Deny lists prefixes to act on.
+	Deny []string `json:"deny"`
+
+	// AllowFile is a file of prefixes to allow, one per line.
+	AllowFile string `json:"allow_file"`
+
+	// DenyFile is a file of prefixes to act on, one per line.
+	DenyFile string `json:"deny_file"`
+}
+
+// CIDRRule allows or acts on accounts by the network prefix of their IP address.
+// It only needs the IP address, so the engine evaluates it before the GeoIP lookup.
+type CIDRRule struct {
+	name string
+	tree *prefixTree
+}
+
+// NewCIDRRule creates a new CIDRRule.
+func NewCIDRRule(name string, params *CIDRParams) (*CIDRRule, error) {
+	r := &CIDRRule{
+		name: name,
+		tree: newPrefixTree(),
+	}
+
+	lists := []struct {
+		items  []string
+		file   string
+		action Action
+	}{
+		{params.Allow, params.AllowFile, ActionAllow},
+		{params.Deny, params.DenyFile, ActionAct},
+	}
+
+	for _, list := range lists {
+		items := list.items
+		if list.file != "" {
+			fileItems, err := readListFile(list.file)
+			if err != nil {
+				return nil, &InvalidRule{Name: name, Err: err}
+			}
+			items = append(items, fileItems...)
+		}
+
+		for _, item := range items {
+			prefix, err := parsePrefix(item)
+			if err != nil {
+				return nil, &InvalidRule{Name: name, Err: err}
+			}
+			r.tree.insert(prefix, list.action)
+		}
+	}
+
+	if r.tree.size == 0 {
+		return nil, &InvalidRule{Name: name, Msg: "no allow or deny prefixes"}
+	}
+	return r, nil
+}
+
+// Name returns the name of the rule.
+func (r *CIDRRule) Name() string {
+	return r.name
+}
+
+// PreLookup reports that the rule only needs the IP address.
+func (r *CIDRRule) PreLookup() bool {
+	return true
+}
+
+// Evaluate allows or acts on accounts whose IP address falls in a listed prefix.
+func (r *CIDRRule) Evaluate(in *Input) (*Result, error) {
+	entry := r.tree.lookup(in.IP)
+	if entry == nil {
+		return nil, nil
+	}
+
+	prefix := entry.prefix.String()
+	if entry.action == ActionAllow {
+		return &Result{
+			Action: ActionAllow,
+			Reason: "IP is in the allowed prefix " + prefix,
+			Match:  prefix,
+		}, nil
+	}
+	return &Result{
+		Action: ActionAct,
+		Reason: "IP is in the denied prefix " + prefix,
+		Match:  prefix,
+	}, nil
+}
@@ -0,0 +1,70 @@
+package policy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// confusables maps characters to the Latin letter they are commonly mistaken for.
+// It is a practical subset of the Unicode confusables data (UTS #39), covering the
+// Cyrillic, Greek, fullwidth, accented and digit substitutions seen in impersonation.
+var confusables = map[rune]rune{
+	// Digits and symbols
+	'0': 'o', '1': 'l', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '9': 'g',
+	'|': 'l', '!': 'l', '$': 's', '@': 'a',
+
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'ё': 'e', 'һ': 'h', 'і': 'l',
+	'ї': 'l', 'ј': 'j', 'к': 'k', 'ӏ': 'l', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'ԛ': 'q', 'ѕ': 's', 'т': 't', 'у': 'y', 'ѵ': 'v', 'ԝ': 'w', 'х': 'x', 'ү': 'y',
+
+	// Greek
+	'α': 'a', 'β': 'b', 'ϲ': 'c', 'ε': 'e', 'η': 'n', 'ι': 'l', 'κ': 'k', 'ν': 'v',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w', 'γ': 'y',
+
+	// Latin look-alikes
+	'ı': 'l', 'ɩ': 'l', 'ℓ': 'l', 'ɡ': 'g', 'ſ': 'f',
+
+	// Accented Latin
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ā': 'a', 'ą': 'a',
+	'ç': 'c', 'ć': 'c', 'č': 'c',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e', 'ē': 'e', 'ę': 'e', 'ě': 'e',
+	'ì': 'l', 'í': 'l', 'î': 'l', 'ï': 'l', 'ī': 'l',
+	'ñ': 'n', 'ń': 'n', 'ň': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o', 'ō': 'o',
+	'ś': 's', 'š': 's', 'ş': 's',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u', 'ū': 'u', 'ů': 'u',
+	'ý': 'y', 'ÿ': 'y',
+	'ź': 'z', 'ż': 'z', 'ž': 'z',
+}
+
+// Skeleton reduces a name to a canonical form so that visually confusable
+// names compare equal (e.g. "AdmIn", "аdmin" with a Cyrillic "а", and "adm1n").
+// Case is folded, separators are removed, and the letters "i" and "l" are
+// treated as the same character. Multi-character look-alikes such as "rn"
+// for "m" are not folded, as they collide with ordinary names ("modern"
+// and "modem").
+func Skeleton(name string) string {
+	var b strings.Builder
+	for _, c := range name {
+		switch c {
+		case '_', '.', '-', ' ':
+			continue
+		}
+
+		// Fullwidth ASCII variants
+		if c >= 0xFF01 && c <= 0xFF5E {
+			c -= 0xFEE0
+		}
+
+		c = unicode.ToLower(c)
+		if mapped, ok := confusables[c]; ok {
+			c = mapped
+		}
+		if c == 'i' {
+			c = 'l'
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/rmrfslashbin/mastoban/pkg/structs"
+)
+
+func TestSkeletonConfusable(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"admin", "AdmIn"},
+		{"admin", "аdmin"}, // Cyrillic а
+		{"admin", "adm1n"},
+		{"admin", "adm!n"},
+		{"admin", "ａｄｍｉｎ"}, // fullwidth
+		{"admin", "ad_min"},
+		{"admin", "ad.m-in"},
+		{"alice", "aIice"},
+		{"alice", "аlісе"}, // Cyrillic а, і, с, е
+		{"mod_alice", "m0d_alice"},
+		{"mod_alice", "mod_аlice"},
+		{"support", "suppοrt"}, // Greek ο
+		{"moderator", "mοdεratοr"},
+		{"rené", "rene"},
+		{"bob", "8ob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if Skeleton(tt.a) != Skeleton(tt.b) {
+				t.Errorf("Skeleton(%q) = %q, Skeleton(%q) = %q, want equal", tt.a, Skeleton(tt.a), tt.b, Skeleton(tt.b))
+			}
+		})
+	}
+}
+
+func TestSkeletonDistinct(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"clara", "dara"},
+		{"modern", "modem"},
+		{"corn", "com"},
+		{"savvy", "sawy"},
+		{"admin", "administrator"},
+		{"alice", "alicia"},
+		{"bob", "rob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if Skeleton(tt.a) == Skeleton(tt.b) {
+				t.Errorf("Skeleton(%q) == Skeleton(%q) == %q, want distinct", tt.a, tt.b, Skeleton(tt.a))
+			}
+		})
+	}
+}
+
+func TestUsernameRuleProtected(t *testing.T) {
+	rule, err := NewUsernameRule("impersonation", &UsernameParams{Protected: []string{"dara", "modem", "alice"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		username string
+		want     bool
+	}{
+		{"dara", true},
+		{"Dara", true},
+		{"dаra", true}, // Cyrillic а
+		{"a1ice", true},
+		{"clara", false},
+		{"modern", false},
+		{"alicia", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			in := &Input{Event: &structs.AccoutCreatedEvent{Object: structs.EventObject{Username: tt.username}}}
+			got, err := rule.Evaluate(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got != nil && got.Action == ActionAct) != tt.want {
+				t.Errorf("Evaluate(%q) = %+v, want act %v", tt.username, got, tt.want)
+			}
+		})
+	}
+}
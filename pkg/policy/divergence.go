@@ -0,0 +1,131 @@
+package policy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/structs"
+)
+
+// Diverges reports whether two decisions for the same account disagree on
+// the action, the suspend level, or what a dry run would have done.
+// Decisions without a level use defaultLevel, the MASTODON_SUSPEND_LEVEL.
+func Diverges(a *Decision, b *Decision, defaultLevel string) bool {
+	level := func(l string) string {
+		if l == "" {
+			return defaultLevel
+		}
+		return l
+	}
+	if a.Action != b.Action || level(a.Level) != level(b.Level) {
+		return true
+	}
+	if (a.WouldAct == nil) != (b.WouldAct == nil) {
+		return true
+	}
+	return a.WouldAct != nil &&
+		(a.WouldAct.Action != b.WouldAct.Action || level(a.WouldAct.Level) != level(b.WouldAct.Level))
+}
+
+// DivergenceSummary counts how often a shadow policy disagreed with the active policy.
+type DivergenceSummary struct {
+	// Decisions is the number of accounts evaluated by the active policy.
+	Decisions int
+
+	// Divergences is the number of accounts the policies disagreed on.
+	Divergences int
+
+	// Actions counts divergences by active and shadow action.
+	Actions []DivergenceCount
+
+	// Rules counts divergences by active and shadow rule.
+	Rules []DivergenceCount
+}
+
+// DivergenceCount is the number of divergences between an active and a shadow value.
+type DivergenceCount struct {
+	Active string
+	Shadow string
+	Count  int
+}
+
+// logLine holds the fields of a worker log line used by SummarizeDivergences
+// and ReadEnforcements.
+type logLine struct {
+	Time       time.Time           `json:"time"`
+	Decision   *structs.Decision   `json:"Decision"`
+	Divergence *structs.Divergence `json:"Divergence"`
+}
+
+// scanLog calls fn for each worker log line (one JSON event per line).
+// Anything before the first '{' on a line is ignored, along with lines
+// that are not JSON.
+func scanLog(r io.Reader, fn func(entry *logLine)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		start := bytes.IndexByte(line, '{')
+		if start < 0 {
+			continue
+		}
+
+		entry := &logLine{}
+		if err := json.Unmarshal(line[start:], entry); err != nil {
+			continue
+		}
+		fn(entry)
+	}
+	return scanner.Err()
+}
+
+// SummarizeDivergences reads worker log lines (one JSON event per line) and
+// summarizes the divergences between the active and shadow policies.
+// Anything before the first '{' on a line is ignored, along with lines
+// that are not JSON.
+func SummarizeDivergences(r io.Reader) (*DivergenceSummary, error) {
+	summary := &DivergenceSummary{}
+	actions := make(map[DivergenceCount]int)
+	rules := make(map[DivergenceCount]int)
+
+	err := scanLog(r, func(entry *logLine) {
+		if entry.Decision != nil {
+			summary.Decisions++
+		}
+		if entry.Divergence != nil {
+			summary.Divergences++
+			actions[DivergenceCount{Active: entry.Divergence.Active.Action, Shadow: entry.Divergence.Shadow.Action}]++
+			rules[DivergenceCount{Active: entry.Divergence.Active.Rule, Shadow: entry.Divergence.Shadow.Rule}]++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Actions = divergenceCounts(actions)
+	summary.Rules = divergenceCounts(rules)
+	return summary, nil
+}
+
+// divergenceCounts flattens the counts, most frequent first.
+func divergenceCounts(counts map[DivergenceCount]int) []DivergenceCount {
+	list := []DivergenceCount{}
+	for key, count := range counts {
+		key.Count = count
+		list = append(list, key)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		if list[i].Active != list[j].Active {
+			return list[i].Active < list[j].Active
+		}
+		return list[i].Shadow < list[j].Shadow
+	})
+	return list
+}
@@ -0,0 +1,66 @@
+package policy
+
+import "testing"
+
+func TestDiverges(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *Decision
+		b    *Decision
+		want bool
+	}{
+		{
+			name: "same",
+			a:    &Decision{Action: ActionAct, Level: "silence"},
+			b:    &Decision{Action: ActionAct, Level: "silence"},
+		},
+		{
+			name: "different action",
+			a:    &Decision{Action: ActionAct},
+			b:    &Decision{Action: ActionAllow},
+			want: true,
+		},
+		{
+			name: "different level",
+			a:    &Decision{Action: ActionAct, Level: "silence"},
+			b:    &Decision{Action: ActionAct, Level: "suspend"},
+			want: true,
+		},
+		{
+			name: "empty level is the default",
+			a:    &Decision{Action: ActionAct},
+			b:    &Decision{Action: ActionAct, Level: "suspend"},
+		},
+		{
+			name: "empty level is not another level",
+			a:    &Decision{Action: ActionAct},
+			b:    &Decision{Action: ActionAct, Level: "silence"},
+			want: true,
+		},
+		{
+			name: "only one would act",
+			a:    &Decision{Action: ActionAllow, WouldAct: &Decision{Action: ActionAct}},
+			b:    &Decision{Action: ActionAllow},
+			want: true,
+		},
+		{
+			name: "would act with the default level",
+			a:    &Decision{Action: ActionAllow, WouldAct: &Decision{Action: ActionAct, Level: "suspend"}},
+			b:    &Decision{Action: ActionAllow, WouldAct: &Decision{Action: ActionAct}},
+		},
+		{
+			name: "would act at different levels",
+			a:    &Decision{Action: ActionAllow, WouldAct: &Decision{Action: ActionAct, Level: "silence"}},
+			b:    &Decision{Action: ActionAllow, WouldAct: &Decision{Action: ActionAct}},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diverges(tt.a, tt.b, "suspend"); got != tt.want {
+				t.Errorf("Diverges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
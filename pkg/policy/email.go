@@ -0,0 +1,164 @@
+package policy
+
+import (
+	"strings"
+)
+
+// EmailParams configures an EmailRule. Domain entries match the domain
+// exactly; entries starting with "*." match any subdomain of the domain
+// (e.g. "*.example.com" matches "mail.example.com" but not "example.com").
+//
+// Deny and disposable matches take precedence over allow matches.
+type EmailParams struct {
+	// Allow lists email domains to allow, skipping the remaining rules.
+	Allow []string `json:"allow"`
+
+	// Deny lists email domains to act on.
+	Deny []string `json:"deny"`
+
+	// AllowFile is a file of email domains to allow, one per line.
+	AllowFile string `json:"allow_file"`
+
+	// DenyFile is a file of email domains to act on, one per line.
+	DenyFile string `json:"deny_file"`
+
+	// DisposableFile is a file of disposable email domains to act on, one per line.
+	// Entries match the domain and any of its subdomains.
+	DisposableFile string `json:"disposable_file"`
+}
+
+// EmailRule allows or acts on accounts by the domain of their email address.
+type EmailRule struct {
+	name       string
+	allow      *domainMatcher
+	deny       *domainMatcher
+	disposable *domainMatcher
+}
+
+// NewEmailRule creates a new EmailRule.
+func NewEmailRule(name string, params *EmailParams) (*EmailRule, error) {
+	r := &EmailRule{name: name}
+
+	var err error
+	if r.allow, err = newDomainMatcher(params.Allow, params.AllowFile, false); err != nil {
+		return nil, &InvalidRule{Name: name, Err: err}
+	}
+	if r.deny, err = newDomainMatcher(params.Deny, params.DenyFile, false); err != nil {
+		return nil, &InvalidRule{Name: name, Err: err}
+	}
+	if r.disposable, err = newDomainMatcher(nil, params.DisposableFile, true); err != nil {
+		return nil, &InvalidRule{Name: name, Err: err}
+	}
+
+	if r.allow.size() == 0 && r.deny.size() == 0 && r.disposable.size() == 0 {
+		return nil, &InvalidRule{Name: name, Msg: "no allow, deny or disposable domains"}
+	}
+	return r, nil
+}
+
+// Name returns the name of the rule.
+func (r *EmailRule) Name() string {
+	return r.name
+}
+
+// Evaluate allows or acts on accounts whose email domain is listed.
+func (r *EmailRule) Evaluate(in *Input) (*Result, error) {
+	domain := EmailDomain(in.Event.Object.Email)
+	if domain == "" {
+		return nil, nil
+	}
+
+	if match := r.deny.match(domain); match != "" {
+		return &Result{
+			Action: ActionAct,
+			Reason: "email domain '" + domain + "' is in the deny list",
+			Match:  match,
+		}, nil
+	}
+	if match := r.disposable.match(domain); match != "" {
+		return &Result{
+			Action: ActionAct,
+			Reason: "email domain '" + domain + "' is a disposable email domain",
+			Match:  match,
+		}, nil
+	}
+	if match := r.allow.match(domain); match != "" {
+		return &Result{
+			Action: ActionAllow,
+			Reason: "email domain '" + domain + "' is in the allow list",
+			Match:  match,
+		}, nil
+	}
+	return nil, nil
+}
+
+// EmailDomain returns the lower-cased domain of an email address, or "" if there is none.
+func EmailDomain(email string) string {
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(email[i+1:])), ".")
+}
+
+// domainMatcher matches domains against exact and wildcard entries.
+type domainMatcher struct {
+	exact    map[string]struct{}
+	wildcard map[string]struct{}
+}
+
+// newDomainMatcher builds a domainMatcher from a list of domains and an optional list file.
+// When subdomains is true, every entry also matches its subdomains.
+func newDomainMatcher(items []string, listFile string, subdomains bool) (*domainMatcher, error) {
+	if listFile != "" {
+		fileItems, err := readListFile(listFile)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, fileItems...)
+	}
+
+	m := &domainMatcher{
+		exact:    make(map[string]struct{}),
+		wildcard: make(map[string]struct{}),
+	}
+	for _, item := range items {
+		item = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(item)), ".")
+		if strings.HasPrefix(item, "*.") {
+			m.wildcard[strings.TrimPrefix(item, "*.")] = struct{}{}
+			continue
+		}
+		if item == "" || strings.ContainsAny(item, "*@ ") {
+			return nil, &InvalidPolicy{Msg: "invalid domain '" + item + "'"}
+		}
+		m.exact[item] = struct{}{}
+		if subdomains {
+			m.wildcard[item] = struct{}{}
+		}
+	}
+	return m, nil
+}
+
+// size returns the number of entries in the matcher.
+func (m *domainMatcher) size() int {
+	return len(m.exact) + len(m.wildcard)
+}
+
+// match returns the entry matching domain, or "" if there is none.
+func (m *domainMatcher) match(domain string) string {
+	if _, ok := m.exact[domain]; ok {
+		return domain
+	}
+
+	// Walk up the parent domains looking for a wildcard entry
+	for parent := domain; ; {
+		i := strings.Index(parent, ".")
+		if i < 0 {
+			return ""
+		}
+		parent = parent[i+1:]
+		if _, ok := m.wildcard[parent]; ok {
+			return "*." + parent
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package policy
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/mastoclient"
+	"github.com/rmrfslashbin/mastoban/pkg/velocity"
+)
+
+// EmailBlockParams configures the Mastodon email blocks created
+// for accounts the rule acts on or rejects.
+type EmailBlockParams struct {
+	// DomainThreshold blocks an email domain once this many accounts
+	// from it have been acted on within Window. Zero never blocks domains.
+	// Counts are kept in the velocity store, which must be persistent.
+	DomainThreshold int `json:"domain_threshold,omitempty"`
+
+	// Window is how far back to count accounts acted on, e.g. "168h".
+	Window string `json:"window,omitempty"`
+
+	// Exclude lists email domains, e.g. large free mail providers,
+	// that are never blocked. Subdomains are excluded too.
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Canonical also blocks the email address of every account rejected,
+	// or acted on at one of Levels, so it can't be used again with dots
+	// or a +suffix.
+	Canonical bool `json:"canonical,omitempty"`
+
+	// Levels are the suspend levels that get a canonical email block.
+	// Defaults to disable and suspend.
+	Levels []string `json:"levels,omitempty"`
+
+	// DryRun records the blocks that would have been created
+	// without creating them.
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// window is the parsed Window.
+	window time.Duration
+}
+
+// EmailBlock is the Mastodon email blocks to create for a decision.
+type EmailBlock struct {
+	// Domain is the email domain to block, once it has passed the threshold.
+	Domain string
+
+	// Count is the number of accounts from the email domain acted on within the window.
+	Count int
+
+	// Email is the email address to block, if canonical blocks are enabled.
+	Email string
+
+	// DryRun means the blocks are recorded, not created.
+	DryRun bool
+}
+
+// validate checks the email block parameters and fills in the defaults.
+func (p *EmailBlockParams) validate(name string) error {
+	if p.DomainThreshold < 0 {
+		return &InvalidRule{Name: name, Msg: "email_block domain_threshold must not be negative"}
+	}
+	if p.DomainThreshold == 0 && !p.Canonical {
+		return &InvalidRule{Name: name, Msg: "email_block needs a domain_threshold or canonical"}
+	}
+
+	if p.Canonical {
+		if len(p.Levels) == 0 {
+			p.Levels = []string{"disable", "suspend"}
+		}
+		for _, level := range p.Levels {
+			if level == "none" || !mastoclient.ValidSuspendLevel(level) {
+				return &InvalidRule{Name: name, Msg: "invalid email_block level '" + level + "'"}
+			}
+		}
+	}
+
+	if p.DomainThreshold == 0 {
+		return nil
+	}
+	window, err := time.ParseDuration(p.Window)
+	if err != nil {
+		return &InvalidRule{Name: name, Msg: "invalid email_block window", Err: err}
+	}
+	if window <= 0 {
+		return &InvalidRule{Name: name, Msg: "email_block window must be greater than zero"}
+	}
+	p.window = window
+	return nil
+}
+
+// canonical reports whether an account acted on at level gets a
+// canonical email block. Rejected accounts always do.
+func (p *EmailBlockParams) canonical(action Action, level string) bool {
+	if !p.Canonical {
+		return false
+	}
+	if action == ActionReject {
+		return true
+	}
+	for _, l := range p.Levels {
+		if strings.EqualFold(l, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether the domain, or a parent domain, is excluded.
+func (p *EmailBlockParams) excluded(domain string) bool {
+	for _, exclude := range p.Exclude {
+		exclude = strings.ToLower(exclude)
+		if domain == exclude || strings.HasSuffix(domain, "."+exclude) {
+			return true
+		}
+	}
+	return false
+}
+
+// EmailBlock counts an enforced decision against the account's email domain
+// and returns the email blocks to create, if the deciding rule configures
+// them. Level is the suspend level applied to the account. Call it once for
+// each decision to act or reject that was enforced.
+func (e *Engine) EmailBlock(d *Decision, level string) (*EmailBlock, error) {
+	if d.emailBlock == nil || d.Input == nil || d.Input.Event == nil {
+		return nil, nil
+	}
+	if d.Action != ActionAct && d.Action != ActionReject {
+		return nil, nil
+	}
+
+	params := d.emailBlock
+	email := d.Input.Event.Object.Email
+	block := &EmailBlock{DryRun: params.DryRun}
+	if email != "" && params.canonical(d.Action, level) {
+		block.Email = email
+	}
+
+	domain := EmailDomain(email)
+	if params.DomainThreshold > 0 && domain != "" && !params.excluded(domain) {
+		key := velocity.SuspendedEmailDomainKey(domain)
+		now := d.Input.Time
+		if now.IsZero() {
+			now = time.Now()
+		}
+		if !e.velocityReadOnly {
+			if err := e.velocity.Record(key, d.Input.Event.Object.Id, now, params.window); err != nil {
+				return nil, &VelocityFailed{Key: key, Msg: "unable to record suspension", Err: err}
+			}
+		}
+		count, err := e.velocity.Count(key, now.Add(-params.window))
+		if err != nil {
+			return nil, &VelocityFailed{Key: key, Msg: "unable to count suspensions", Err: err}
+		}
+		block.Count = count
+		if count >= params.DomainThreshold {
+			block.Domain = domain
+		}
+	}
+
+	if block.Domain == "" && block.Email == "" {
+		return nil, nil
+	}
+	return block, nil
+}
+
+// BlocksEmailDomains reports whether any rule in the policy blocks email
+// domains. Domain blocks count suspensions in the velocity store, so they
+// need a store that is shared and persistent.
+func (p *Policy) BlocksEmailDomains() bool {
+	for i := range p.Rules {
+		if b := p.Rules[i].EmailBlock; b != nil && b.DomainThreshold > 0 {
+			return true
+		}
+	}
+	return false
+}
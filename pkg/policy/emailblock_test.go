@@ -0,0 +1,123 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/velocity"
+)
+
+func TestEmailBlockCanonicalLevels(t *testing.T) {
+	tests := []struct {
+		name   string
+		levels []string
+		action Action
+		level  string
+		want   bool
+	}{
+		{"default suspend", nil, ActionAct, "suspend", true},
+		{"default disable", nil, ActionAct, "disable", true},
+		{"default silence", nil, ActionAct, "silence", false},
+		{"default sensitive", nil, ActionAct, "sensitive", false},
+		{"reject", nil, ActionReject, "", true},
+		{"configured silence", []string{"silence"}, ActionAct, "silence", true},
+		{"configured, other level", []string{"silence"}, ActionAct, "suspend", false},
+		{"level case", []string{"Suspend"}, ActionAct, "suspend", true},
+	}
+
+	e := &Engine{velocity: velocity.NewMemoryStore()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := &EmailBlockParams{Canonical: true, Levels: tt.levels}
+			if err := params.validate("email-block"); err != nil {
+				t.Fatalf("validate(): %v", err)
+			}
+			d := &Decision{Action: tt.action, Input: newTestInput("1", "192.0.2.1"), emailBlock: params}
+			block, err := e.EmailBlock(d, tt.level)
+			if err != nil {
+				t.Fatalf("EmailBlock(): %v", err)
+			}
+			if got := block != nil && block.Email == "user1@example.com"; got != tt.want {
+				t.Errorf("EmailBlock() = %+v, want canonical block %v", block, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailBlockDomainThreshold(t *testing.T) {
+	e := &Engine{velocity: velocity.NewMemoryStore()}
+	params := &EmailBlockParams{DomainThreshold: 3, Window: "24h", Exclude: []string{"gmail.com"}}
+	if err := params.validate("email-block"); err != nil {
+		t.Fatalf("validate(): %v", err)
+	}
+
+	// Decisions are made in order, against the same counts. A redelivered
+	// event has the same account and time.
+	at := time.Now()
+	decisions := []struct {
+		name   string
+		id     string
+		email  string
+		action Action
+		want   string
+	}{
+		{"first", "a", "user@SPAM.example", ActionAct, ""},
+		{"second", "b", "user@SPAM.example", ActionAct, ""},
+		{"threshold reached", "c", "user@SPAM.example", ActionAct, "spam.example"},
+		{"above threshold", "d", "user@spam.example", ActionAct, "spam.example"},
+
+		// Redelivered events count once
+		{"other domain", "z", "user@other.example", ActionAct, ""},
+		{"other domain, redelivered", "z", "user@other.example", ActionAct, ""},
+		{"other domain, redelivered again", "z", "user@other.example", ActionAct, ""},
+
+		// Excluded domains and their subdomains are never blocked
+		{"excluded", "m", "user@mail.gmail.com", ActionAct, ""},
+		{"excluded, second", "n", "user@mail.gmail.com", ActionAct, ""},
+		{"excluded, third", "o", "user@mail.gmail.com", ActionAct, ""},
+
+		// Decisions that don't act are not counted
+		{"hold", "h", "user@hold.example", ActionHold, ""},
+		{"hold, second", "i", "user@hold.example", ActionHold, ""},
+		{"hold, third", "j", "user@hold.example", ActionHold, ""},
+	}
+
+	for _, tt := range decisions {
+		in := newTestInput(tt.id, "192.0.2.1")
+		in.Event.Object.Email = tt.email
+		in.Time = at
+		block, err := e.EmailBlock(&Decision{Action: tt.action, Input: in, emailBlock: params}, "silence")
+		if err != nil {
+			t.Fatalf("%s: EmailBlock(): %v", tt.name, err)
+		}
+		got := ""
+		if block != nil {
+			got = block.Domain
+		}
+		if got != tt.want {
+			t.Errorf("%s: domain = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEmailBlockParamsInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *EmailBlockParams
+	}{
+		{"nothing to block", &EmailBlockParams{}},
+		{"negative threshold", &EmailBlockParams{DomainThreshold: -1, Window: "1h"}},
+		{"missing window", &EmailBlockParams{DomainThreshold: 1}},
+		{"zero window", &EmailBlockParams{DomainThreshold: 1, Window: "0s"}},
+		{"invalid level", &EmailBlockParams{Canonical: true, Levels: []string{"ban"}}},
+		{"level none", &EmailBlockParams{Canonical: true, Levels: []string{"none"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.validate("email-block"); err == nil {
+				t.Errorf("validate() succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,644 @@
+package policy
+
+import (
+	"net"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/geoip"
+	"github.com/rmrfslashbin/mastoban/pkg/structs"
+	"github.com/rmrfslashbin/mastoban/pkg/velocity"
+	"github.com/rs/zerolog"
+)
+
+// Action is the outcome of a policy evaluation.
+type Action string
+
+const (
+	// ActionAllow leaves the account alone.
+	ActionAllow Action = "allow"
+
+	// ActionAct takes moderation action against the account.
+	ActionAct Action = "act"
+
+	// ActionHold leaves the account for a moderator to review.
+	ActionHold Action = "hold"
+
+	// ActionApprove approves a pending account.
+	ActionApprove Action = "approve"
+
+	// ActionReject rejects a pending account. Accounts that
+	// are already approved are acted on instead.
+	ActionReject Action = "reject"
+
+	// ActionError means the account could not be evaluated.
+	ActionError Action = "error"
+)
+
+// enforced reports whether the action calls Mastodon, and so
+// is recorded in WouldAct rather than taken in dry run mode.
+func enforced(action Action) bool {
+	switch action {
+	case ActionAct, ActionApprove, ActionReject:
+		return true
+	}
+	return false
+}
+
+// Input is passed to every rule. It holds the parsed event
+// along with the enrichment data gathered by the engine.
+type Input struct {
+	// Event is the account.created event being evaluated.
+	Event *structs.AccoutCreatedEvent
+
+	// IP is the parsed account IP address.
+	IP net.IP
+
+	// Time is when the event happened, used for rule schedules.
+	Time time.Time
+
+	// IPClass is the class of IP. It is ClassPublic for addresses
+	// with a GeoIP country.
+	IPClass IPClass
+
+	// GeoIP is the GeoIP data for IP.
+	// It is nil while PreLookup rules are evaluated.
+	GeoIP *geoip.GeoIPData
+
+	// Velocity holds the recent signup counts for the velocity rules.
+	// It is empty while PreLookup rules are evaluated, and for
+	// addresses in the account's IP history.
+	Velocity []VelocityCount
+}
+
+// Result is returned by a rule that reached a decision.
+type Result struct {
+	// Action to take.
+	Action Action
+
+	// Reason is a short, human readable explanation.
+	Reason string
+
+	// Match is the list entry that matched, if any (e.g. a CIDR prefix).
+	Match string
+}
+
+// Rule is implemented by every policy rule.
+type Rule interface {
+	// Name returns the name of the rule.
+	Name() string
+
+	// Evaluate returns a Result when the rule reaches a decision,
+	// or nil when the rule has no opinion on the input.
+	Evaluate(in *Input) (*Result, error)
+}
+
+// PreLookupRule is implemented by rules that only need the IP address.
+// The engine evaluates these rules, in order, before the GeoIP lookup.
+type PreLookupRule interface {
+	Rule
+
+	// PreLookup returns true if the rule should run before the GeoIP lookup.
+	PreLookup() bool
+}
+
+// Decision is the final outcome of evaluating an account against the policy.
+type Decision struct {
+	// Action to take.
+	Action Action
+
+	// Rule is the name of the rule that reached the decision.
+	// It is empty when no rule matched.
+	Rule string
+
+	// Reason is a short, human readable explanation.
+	Reason string
+
+	// Match is the list entry that matched, if any (e.g. a CIDR prefix).
+	Match string
+
+	// Level is the suspend level to apply when Action is ActionAct.
+	// It is empty when the default level should be used.
+	Level string
+
+	// Text is the rendered suspend text. It is empty when
+	// the default text should be used.
+	Text string
+
+	// IPBlock is the Mastodon IP block to create along with the action,
+	// if the rule asks for one.
+	IPBlock *IPBlock
+
+	// FollowUp is the follow-up action to schedule when Action is
+	// ActionAct, if the rule asks for one.
+	FollowUp *FollowUp
+
+	// emailBlock is the deciding rule's email block parameters, see EmailBlock.
+	emailBlock *EmailBlockParams
+
+	// Score is the total weight of the weighted rules that matched.
+	Score int
+
+	// Signals lists the weighted rules that matched, making up Score.
+	Signals []Signal
+
+	// WouldAct is the decision a dry run rule or threshold would have made,
+	// or the decision that was not enforced because the engine is in dry run
+	// mode. It is nil when nothing in dry run would have acted.
+	WouldAct *Decision
+
+	// Rules lists every rule checked, in order, with its outcome.
+	Rules []structs.RuleOutcome
+
+	// IPs lists the decision for each IP address in the account's history,
+	// starting with the account IP. It is empty when there is no history.
+	IPs []IPDecision
+
+	// DecidingIP is the address in the account's history that the
+	// decision came from, when it isn't the account IP. Input and Rules
+	// are always those of the account IP.
+	DecidingIP *IPDecision
+
+	// Policy and PolicyVersion identify the policy document, if any.
+	Policy        string
+	PolicyVersion string
+
+	// Input is the data the decision was based on.
+	Input *Input
+
+	// Err is set when Action is ActionError.
+	Err error
+}
+
+// Record returns the decision as a structs.Decision, for logging and output.
+func (d *Decision) Record() *structs.Decision {
+	record := &structs.Decision{
+		Account:       d.Input.Event.Object,
+		Enrichment:    enrichment(d.Input),
+		Rules:         d.Rules,
+		Action:        string(d.Action),
+		Rule:          d.Rule,
+		Reason:        d.Reason,
+		Match:         d.Match,
+		Level:         d.Level,
+		Score:         d.Score,
+		Policy:        d.Policy,
+		PolicyVersion: d.PolicyVersion,
+	}
+	if record.Rules == nil {
+		record.Rules = []structs.RuleOutcome{}
+	}
+	if d.IPBlock != nil {
+		record.IPBlock = &structs.IPBlock{IP: d.IPBlock.Network.String(), Severity: d.IPBlock.Severity}
+		if d.IPBlock.ExpiresIn > 0 {
+			record.IPBlock.ExpiresIn = d.IPBlock.ExpiresIn.String()
+		}
+	}
+	if d.FollowUp != nil {
+		record.FollowUp = &structs.FollowUp{After: d.FollowUp.After.String(), Level: d.FollowUp.Level, Lift: d.FollowUp.Lift}
+	}
+	for _, ip := range d.IPs {
+		record.IPs = append(record.IPs, ip.outcome())
+	}
+	if d.DecidingIP != nil {
+		deciding := d.DecidingIP.outcome()
+		deciding.Rules = d.DecidingIP.Decision.Rules
+		record.DecidingIP = &deciding
+	}
+	if d.WouldAct != nil {
+		record.WouldAct = &structs.RuleOutcome{
+			Rule:    d.WouldAct.Rule,
+			Outcome: string(d.WouldAct.Action),
+			Reason:  d.WouldAct.Reason,
+			Match:   d.WouldAct.Match,
+			Level:   d.WouldAct.Level,
+		}
+	}
+	if d.Err != nil {
+		record.Error = d.Err.Error()
+	}
+	return record
+}
+
+// outcome returns the IP decision as a structs.IPOutcome.
+func (ip *IPDecision) outcome() structs.IPOutcome {
+	outcome := structs.IPOutcome{
+		Enrichment: enrichment(ip.Decision.Input),
+		Action:     string(ip.Decision.Action),
+		Rule:       ip.Decision.Rule,
+		Reason:     ip.Decision.Reason,
+	}
+	if !ip.UsedAt.IsZero() {
+		usedAt := ip.UsedAt
+		outcome.UsedAt = &usedAt
+	}
+	if ip.Decision.Err != nil {
+		outcome.Error = ip.Decision.Err.Error()
+	}
+	return outcome
+}
+
+// enrichment returns the enrichment data gathered for the input.
+func enrichment(in *Input) structs.Enrichment {
+	data := structs.Enrichment{IP: in.Event.Object.Ip, IPClass: string(in.IPClass)}
+	if in.IP != nil {
+		data.IP = in.IP.String()
+	}
+	if in.GeoIP != nil {
+		data.Country = in.GeoIP.Country
+		data.Continent = in.GeoIP.Continent
+		data.ASN = in.GeoIP.ASN
+		data.ASOrganization = in.GeoIP.ASOrganization
+	}
+	for _, count := range in.Velocity {
+		data.Velocity = append(data.Velocity, structs.VelocityCount{
+			Key:    count.Key,
+			Window: count.Window.String(),
+			Count:  count.Count,
+		})
+	}
+	return data
+}
+
+// Option for the policy engine
+type Option func(e *Engine)
+
+// Engine evaluates accounts against an ordered list of rules.
+type Engine struct {
+	log       *zerolog.Logger
+	geoIP     *geoip.GeoIP
+	policy    *Policy
+	rules     []Rule
+	ipClasses map[IPClass]Action
+	ipMode    IPMode
+	approval  bool
+	dryRun    bool
+
+	velocity         velocity.Store
+	velocityReadOnly bool
+}
+
+// New creates a new policy engine.
+func New(opts ...Option) (*Engine, error) {
+	e := &Engine{}
+
+	// apply the list of options to Engine
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	// Check to ensure the GeoIP instance is set
+	if e.geoIP == nil {
+		return nil, &NoGeoIP{}
+	}
+
+	// Set the action for each IP class, using the defaults unless the policy overrides them
+	classes := map[IPClass]Action{}
+	if e.policy != nil {
+		classes = e.policy.IPClasses
+	}
+	ipClasses, err := ipClassActions(classes)
+	if err != nil {
+		return nil, err
+	}
+	e.ipClasses = ipClasses
+
+	// Build the rules defined by the policy document, ahead of any added with WithRules()
+	if e.policy != nil {
+		rules, err := e.policy.Build()
+		if err != nil {
+			return nil, err
+		}
+		e.rules = append(append([]Rule{}, rules...), e.rules...)
+		e.dryRun = e.dryRun || e.policy.DryRun
+		e.ipMode = e.policy.IPMode
+		e.approval = e.policy.Approval
+	}
+
+	// keep velocity counts in memory if no store is provided
+	if e.velocity == nil {
+		e.velocity = velocity.NewMemoryStore()
+	}
+
+	// set up logger if not provided
+	if e.log == nil {
+		log := zerolog.New(os.Stderr).With().Timestamp().Logger()
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		e.log = &log
+	}
+	return e, nil
+}
+
+// WithDryRun puts the engine in dry run mode. Decisions to act are
+// replaced by ActionAllow, with the decision recorded in WouldAct.
+func WithDryRun(dryRun bool) Option {
+	return func(e *Engine) {
+		e.dryRun = dryRun
+	}
+}
+
+// WithGeoIP sets the GeoIP instance used to enrich accounts
+func WithGeoIP(geoIP *geoip.GeoIP) Option {
+	return func(e *Engine) {
+		e.geoIP = geoIP
+	}
+}
+
+// WithLogger sets the logger to use
+func WithLogger(log *zerolog.Logger) Option {
+	return func(e *Engine) {
+		e.log = log
+	}
+}
+
+// WithPolicy sets the policy document to build rules from
+func WithPolicy(p *Policy) Option {
+	return func(e *Engine) {
+		e.policy = p
+	}
+}
+
+// WithVelocityStore sets the store used to count signups for velocity rules.
+// Defaults to a velocity.MemoryStore.
+func WithVelocityStore(store velocity.Store) Option {
+	return func(e *Engine) {
+		e.velocity = store
+	}
+}
+
+// WithVelocityReadOnly counts signups without recording them, for an engine
+// that shares a store with another engine that records every signup, e.g. a
+// shadow policy evaluated after the active policy.
+func WithVelocityReadOnly() Option {
+	return func(e *Engine) {
+		e.velocityReadOnly = true
+	}
+}
+
+// WithRules appends rules to the engine. Rules are evaluated in the order added.
+func WithRules(rules ...Rule) Option {
+	return func(e *Engine) {
+		e.rules = append(e.rules, rules...)
+	}
+}
+
+// Evaluate enriches the account and runs it through the rules.
+// The IP address is classified first, and addresses in a private or reserved
+// range are handled by the action for their class. PreLookup rules are
+// evaluated next, then the IP address is looked up in the GeoIP database;
+// addresses without a country are handled by the unknown_country class, and
+// the remaining rules are evaluated. The first unweighted
+// rule to return a Result decides. Weighted rules add to the risk score
+// instead; if no rule decides, the score is checked against the policy
+// thresholds, and below the thresholds the account is allowed.
+//
+// The decision is then adjusted for the account's approval state, see
+// Policy.Approval.
+//
+// Dry run rules and thresholds never decide; the first one that would have
+// acted is recorded in the decision's WouldAct. In dry run mode, every
+// decision to act, approve or reject is recorded in WouldAct and the
+// account is allowed.
+func (e *Engine) Evaluate(event *structs.AccoutCreatedEvent) *Decision {
+	decision := e.evaluateHistory(event, e.evaluateIP(event, event.Object.Ip))
+	decision = e.applyApproval(decision)
+	if e.dryRun && enforced(decision.Action) {
+		wouldAct := *decision
+		wouldAct.WouldAct = nil
+		decision = &Decision{
+			Action:        ActionAllow,
+			Reason:        "dry run",
+			Score:         decision.Score,
+			Signals:       decision.Signals,
+			WouldAct:      &wouldAct,
+			Rules:         wouldAct.Rules,
+			IPs:           wouldAct.IPs,
+			DecidingIP:    wouldAct.DecidingIP,
+			Policy:        wouldAct.Policy,
+			PolicyVersion: wouldAct.PolicyVersion,
+			Input:         wouldAct.Input,
+		}
+	}
+	return decision
+}
+
+// evaluateIP evaluates the account as if it came from the given IP address.
+func (e *Engine) evaluateIP(event *structs.AccoutCreatedEvent, ip string) *Decision {
+	ev := &evaluation{in: &Input{Event: event, Time: eventTime(event)}}
+	decision := e.evaluate(ev, ip)
+	decision.Rules = ev.outcomes
+	if e.policy != nil {
+		decision.Policy = e.policy.Name
+		decision.PolicyVersion = e.policy.Version
+	}
+	return decision
+}
+
+// evaluate runs the account through the rules and thresholds.
+func (e *Engine) evaluate(ev *evaluation, ip string) *Decision {
+	// Parse the IP address
+	ev.in.IP = net.ParseIP(ip)
+	if ev.in.IP == nil {
+		return ev.decision(&Decision{Action: ActionError, Err: &InvalidIP{IP: ip}})
+	}
+
+	// Classify the IP address before running any rules
+	ev.in.IPClass = ClassifyIP(ev.in.IP)
+	if decision := e.evaluateIPClass(ev); decision != nil {
+		return decision
+	}
+
+	// Split the rules by whether they need the GeoIP lookup
+	preLookup := []Rule{}
+	postLookup := []Rule{}
+	for _, rule := range e.rules {
+		if r, ok := rule.(PreLookupRule); ok && r.PreLookup() {
+			preLookup = append(preLookup, rule)
+		} else {
+			postLookup = append(postLookup, rule)
+		}
+	}
+
+	if decision := e.evaluateRules(preLookup, ev); decision != nil {
+		return decision
+	}
+
+	// Lookup the IP address in the GeoIP database
+	ipData, err := e.geoIP.Lookup(ev.in.IP)
+	if err != nil {
+		return ev.decision(&Decision{Action: ActionError, Err: &LookupFailed{IP: ev.in.IP.String(), Err: err}})
+	}
+	ev.in.GeoIP = ipData
+
+	// Addresses without a country can't be judged by where they are
+	if ev.in.IPClass == ClassPublic && ipData.Country == "" {
+		ev.in.IPClass = ClassUnknownCountry
+		if decision := e.evaluateIPClass(ev); decision != nil {
+			return decision
+		}
+	}
+
+	// Count the signup for velocity rules, once per account
+	if ip == ev.in.Event.Object.Ip {
+		if err := e.observeVelocity(ev.in); err != nil {
+			return ev.decision(&Decision{Action: ActionError, Err: err})
+		}
+	}
+
+	if decision := e.evaluateRules(postLookup, ev); decision != nil {
+		return decision
+	}
+
+	// No rule decided, so check the risk score against the thresholds
+	if e.policy != nil {
+		if decision := e.policy.evaluateThresholds(ev); decision != nil {
+			return decision
+		}
+	}
+
+	return ev.decision(&Decision{Action: ActionAllow, Reason: "no rule matched"})
+}
+
+// evaluateIPClass returns the decision for the class of the IP address, or
+// nil when the address is public or its class is evaluated against the rules.
+func (e *Engine) evaluateIPClass(ev *evaluation) *Decision {
+	if ev.in.IPClass == ClassPublic {
+		return nil
+	}
+	action := e.ipClasses[ev.in.IPClass]
+	if action == ActionEvaluate {
+		return nil
+	}
+
+	decision := ev.decision(&Decision{
+		Action: action,
+		Rule:   IPClassRule,
+		Reason: "IP address is in the '" + string(ev.in.IPClass) + "' class",
+		Match:  string(ev.in.IPClass),
+	})
+	ev.outcome(decision, false)
+	return decision
+}
+
+// evaluateRules runs the input through the rules in order and returns the
+// decision of the first unweighted rule to return a Result, or nil.
+func (e *Engine) evaluateRules(rules []Rule, ev *evaluation) *Decision {
+	for _, rule := range rules {
+		// Skip rules outside their schedule
+		if r, ok := rule.(*definedRule); ok {
+			if reason := r.inactive(ev.in.Time); reason != "" {
+				ev.outcomes = append(ev.outcomes, structs.RuleOutcome{Rule: rule.Name(), Outcome: "inactive", Reason: reason})
+				continue
+			}
+		}
+
+		res, err := rule.Evaluate(ev.in)
+		if err != nil {
+			ev.outcomes = append(ev.outcomes, structs.RuleOutcome{Rule: rule.Name(), Outcome: string(ActionError), Reason: err.Error()})
+			return ev.decision(&Decision{Action: ActionError, Rule: rule.Name(), Err: &RuleFailed{Rule: rule.Name(), Err: err}})
+		}
+		if res == nil {
+			ev.outcomes = append(ev.outcomes, structs.RuleOutcome{Rule: rule.Name(), Outcome: "no_opinion"})
+			e.log.Trace().
+				Str("rule", rule.Name()).
+				Str("UserID", ev.in.Event.Object.Id).
+				Msg("rule has no opinion")
+			continue
+		}
+
+		weight, dryRun := 0, false
+		r, defined := rule.(*definedRule)
+		if defined {
+			weight, dryRun = r.def.Weight, r.def.DryRun
+		}
+
+		// Weighted rules add to the score rather than deciding.
+		// Dry run signals are recorded but not counted.
+		if weight != 0 {
+			outcome := structs.RuleOutcome{Rule: rule.Name(), Outcome: string(res.Action), Reason: res.Reason, Match: res.Match, DryRun: dryRun}
+			if res.Action != ActionAllow {
+				if !dryRun {
+					ev.score += weight
+				}
+				ev.signals = append(ev.signals, Signal{Rule: rule.Name(), Weight: weight, Reason: res.Reason, Match: res.Match, DryRun: dryRun})
+				outcome.Weight = weight
+			}
+			ev.outcomes = append(ev.outcomes, outcome)
+			continue
+		}
+
+		decision := ev.decision(&Decision{Action: res.Action, Rule: rule.Name(), Reason: res.Reason, Match: res.Match})
+		if defined {
+			decision.Level = r.def.Level
+			if r.def.IPBlock != nil && (decision.Action == ActionAct || decision.Action == ActionReject) {
+				decision.IPBlock = r.def.IPBlock.block(ev.in.IP)
+			}
+			if r.def.EmailBlock != nil && (decision.Action == ActionAct || decision.Action == ActionReject) {
+				decision.emailBlock = r.def.EmailBlock
+			}
+			if r.def.FollowUp != nil && decision.Action == ActionAct {
+				decision.FollowUp = r.def.FollowUp.followUp()
+			}
+			decision = ev.withText(r.text, decision)
+		}
+		ev.outcome(decision, dryRun)
+
+		if dryRun && decision.Action != ActionError {
+			ev.dryRun(decision)
+			continue
+		}
+		return decision
+	}
+	return nil
+}
+
+// evaluation holds the state of a single call to Evaluate.
+type evaluation struct {
+	in       *Input
+	score    int
+	signals  []Signal
+	outcomes []structs.RuleOutcome
+	wouldAct *Decision
+}
+
+// outcome records the outcome of the rule or threshold that made the decision.
+func (ev *evaluation) outcome(d *Decision, dryRun bool) {
+	outcome := structs.RuleOutcome{Rule: d.Rule, Outcome: string(d.Action), Reason: d.Reason, Match: d.Match, Level: d.Level, DryRun: dryRun}
+	if d.Err != nil {
+		outcome.Reason = d.Err.Error()
+	}
+	ev.outcomes = append(ev.outcomes, outcome)
+}
+
+// dryRun records the decision of a dry run rule or threshold
+// if it would have been enforced and nothing else in dry run has.
+func (ev *evaluation) dryRun(d *Decision) {
+	if enforced(d.Action) && ev.wouldAct == nil {
+		ev.wouldAct = d
+	}
+}
+
+// withText renders the suspend text template, if any, for a decision to act
+// or reject, since rejecting an approved account acts on it instead. A
+// template that fails to render turns the decision into an error.
+func (ev *evaluation) withText(tmpl *template.Template, d *Decision) *Decision {
+	if tmpl == nil || (d.Action != ActionAct && d.Action != ActionReject) {
+		return d
+	}
+	text, err := renderText(tmpl, d)
+	if err != nil {
+		return ev.decision(&Decision{Action: ActionError, Rule: d.Rule, Err: &RuleFailed{Rule: d.Rule, Msg: "unable to render suspend text", Err: err}})
+	}
+	d.Text = text
+	return d
+}
+
+// decision completes the decision with the input and score.
+func (ev *evaluation) decision(d *Decision) *Decision {
+	d.Input = ev.in
+	d.Score = ev.score
+	d.Signals = ev.signals
+	d.WouldAct = ev.wouldAct
+	return d
+}
@@ -0,0 +1,216 @@
+package policy
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/geoip"
+	"github.com/rmrfslashbin/mastoban/pkg/structs"
+	"github.com/rs/zerolog"
+)
+
+// newTestInput returns an input for account id signing up now from ip,
+// a US address on AS14061. Tests change the fields they need.
+func newTestInput(id string, ip string) *Input {
+	return &Input{
+		Event: &structs.AccoutCreatedEvent{
+			Object: structs.EventObject{
+				Id:       id,
+				Username: "user" + id,
+				Email:    "user" + id + "@example.com",
+				Locale:   "en",
+				Ip:       ip,
+			},
+		},
+		IP:      net.ParseIP(ip),
+		Time:    time.Now(),
+		IPClass: ClassPublic,
+		GeoIP: &geoip.GeoIPData{
+			IP:             net.ParseIP(ip),
+			Continent:      "NA",
+			Country:        "US",
+			ASN:            14061,
+			ASOrganization: "DIGITALOCEAN",
+		},
+	}
+}
+
+// newTestEngine returns an engine for the policy. Addresses are looked up in
+// testdata/country.mmdb, where 1.0.0.0/8 is in the US (NA), 2.0.0.0/8 in
+// Russia (EU) and 3.0.0.0/8 in Japan (AS).
+func newTestEngine(t *testing.T, p *Policy, opts ...Option) *Engine {
+	t.Helper()
+	dbfile := "testdata/country.mmdb"
+	geoIP, err := geoip.New(&dbfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := zerolog.Nop()
+	e, err := New(append([]Option{WithGeoIP(geoIP), WithLogger(&log), WithPolicy(p)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// denyRule returns a rule that decides action for accounts from the country.
+func denyRule(name string, country string, action Action, dryRun bool) RuleDefinition {
+	return RuleDefinition{Name: name, Action: action, DryRun: dryRun, Geo: &GeoParams{DenyCountries: []string{country}}}
+}
+
+func TestEvaluateDryRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    *Policy
+		dryRun    bool
+		ip        string
+		action    Action
+		rule      string
+		wouldAct  Action
+		wouldRule string
+	}{
+		{
+			name:      "dry run rule acts",
+			policy:    &Policy{Rules: []RuleDefinition{denyRule("dry", "RU", "", true)}},
+			ip:        "2.0.0.1",
+			action:    ActionAllow,
+			wouldAct:  ActionAct,
+			wouldRule: "dry",
+		},
+		{
+			name:   "dry run rule doesn't match",
+			policy: &Policy{Rules: []RuleDefinition{denyRule("dry", "RU", "", true)}},
+			ip:     "1.0.0.1",
+			action: ActionAllow,
+		},
+		{
+			name:      "enforced rule after a dry run rule",
+			policy:    &Policy{Rules: []RuleDefinition{denyRule("dry", "RU", ActionReject, true), denyRule("live", "RU", "", false)}},
+			ip:        "2.0.0.1",
+			action:    ActionAct,
+			rule:      "live",
+			wouldAct:  ActionReject,
+			wouldRule: "dry",
+		},
+		{
+			name:      "first dry run rule wins",
+			policy:    &Policy{Rules: []RuleDefinition{denyRule("first", "RU", ActionReject, true), denyRule("second", "RU", "", true)}},
+			ip:        "2.0.0.1",
+			action:    ActionAllow,
+			wouldAct:  ActionReject,
+			wouldRule: "first",
+		},
+		{
+			name:   "dry run hold isn't enforced",
+			policy: &Policy{Rules: []RuleDefinition{denyRule("dry", "RU", ActionHold, true)}},
+			ip:     "2.0.0.1",
+			action: ActionAllow,
+		},
+		{
+			name:      "dry run hold is skipped for a later dry run rule",
+			policy:    &Policy{Rules: []RuleDefinition{denyRule("hold", "RU", ActionHold, true), denyRule("act", "RU", "", true)}},
+			ip:        "2.0.0.1",
+			action:    ActionAllow,
+			wouldAct:  ActionAct,
+			wouldRule: "act",
+		},
+		{
+			name:      "policy dry run",
+			policy:    &Policy{DryRun: true, Rules: []RuleDefinition{denyRule("live", "RU", "", false)}},
+			ip:        "2.0.0.1",
+			action:    ActionAllow,
+			wouldAct:  ActionAct,
+			wouldRule: "live",
+		},
+		{
+			name:      "engine dry run",
+			policy:    &Policy{Rules: []RuleDefinition{denyRule("live", "RU", "", false)}},
+			dryRun:    true,
+			ip:        "2.0.0.1",
+			action:    ActionAllow,
+			wouldAct:  ActionAct,
+			wouldRule: "live",
+		},
+		{
+			name:   "engine dry run allows",
+			policy: &Policy{Rules: []RuleDefinition{denyRule("live", "RU", "", false)}},
+			dryRun: true,
+			ip:     "1.0.0.1",
+			action: ActionAllow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, tt.policy, WithDryRun(tt.dryRun))
+			d := e.Evaluate(newTestInput("1", tt.ip).Event)
+
+			if d.Action != tt.action || d.Rule != tt.rule {
+				t.Errorf("Evaluate() = %s by %q, want %s by %q", d.Action, d.Rule, tt.action, tt.rule)
+			}
+			var wouldAct Action
+			var wouldRule string
+			if d.WouldAct != nil {
+				wouldAct, wouldRule = d.WouldAct.Action, d.WouldAct.Rule
+			}
+			if wouldAct != tt.wouldAct || wouldRule != tt.wouldRule {
+				t.Errorf("WouldAct = %q by %q, want %q by %q", wouldAct, wouldRule, tt.wouldAct, tt.wouldRule)
+			}
+			if d.WouldAct != nil && d.WouldAct.WouldAct != nil {
+				t.Errorf("WouldAct.WouldAct = %+v, want nil", d.WouldAct.WouldAct)
+			}
+		})
+	}
+}
+
+func TestEvaluateApproval(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *Policy
+		approved bool
+		ip       string
+		action   Action
+		wouldAct Action
+	}{
+		// Pending accounts
+		{"pending, reject", &Policy{Rules: []RuleDefinition{denyRule("r", "RU", ActionReject, false)}}, false, "2.0.0.1", ActionReject, ""},
+		{"pending, act", &Policy{Rules: []RuleDefinition{denyRule("r", "RU", "", false)}}, false, "2.0.0.1", ActionAct, ""},
+		{"pending, allow", &Policy{Rules: []RuleDefinition{denyRule("r", "RU", "", false)}}, false, "1.0.0.1", ActionAllow, ""},
+		{"pending, approval policy, act", &Policy{Approval: true, Rules: []RuleDefinition{denyRule("r", "RU", "", false)}}, false, "2.0.0.1", ActionReject, ""},
+		{"pending, approval policy, allow", &Policy{Approval: true, Rules: []RuleDefinition{denyRule("r", "RU", "", false)}}, false, "1.0.0.1", ActionApprove, ""},
+		{"pending, approval policy, hold", &Policy{Approval: true, Rules: []RuleDefinition{denyRule("r", "RU", ActionHold, false)}}, false, "2.0.0.1", ActionHold, ""},
+
+		// Approved accounts can't be rejected or approved
+		{"approved, reject", &Policy{Rules: []RuleDefinition{denyRule("r", "RU", ActionReject, false)}}, true, "2.0.0.1", ActionAct, ""},
+		{"approved, approve", &Policy{Rules: []RuleDefinition{denyRule("r", "RU", ActionApprove, false)}}, true, "2.0.0.1", ActionAllow, ""},
+		{"approved, approval policy, act", &Policy{Approval: true, Rules: []RuleDefinition{denyRule("r", "RU", "", false)}}, true, "2.0.0.1", ActionAct, ""},
+		{"approved, approval policy, allow", &Policy{Approval: true, Rules: []RuleDefinition{denyRule("r", "RU", "", false)}}, true, "1.0.0.1", ActionAllow, ""},
+
+		// Dry run decisions are adjusted too
+		{"approved, dry run reject", &Policy{Rules: []RuleDefinition{denyRule("r", "RU", ActionReject, true)}}, true, "2.0.0.1", ActionAllow, ActionAct},
+		{"pending, approval policy, dry run act", &Policy{Approval: true, Rules: []RuleDefinition{denyRule("r", "RU", "", true)}}, false, "2.0.0.1", ActionApprove, ActionReject},
+		{"approved, policy dry run reject", &Policy{DryRun: true, Rules: []RuleDefinition{denyRule("r", "RU", ActionReject, false)}}, true, "2.0.0.1", ActionAllow, ActionAct},
+		{"pending, approval policy dry run", &Policy{DryRun: true, Approval: true, Rules: []RuleDefinition{denyRule("r", "RU", "", false)}}, false, "1.0.0.1", ActionAllow, ActionApprove},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, tt.policy)
+			event := newTestInput("1", tt.ip).Event
+			event.Object.Approved = tt.approved
+			d := e.Evaluate(event)
+
+			if d.Action != tt.action {
+				t.Errorf("Evaluate() = %s, want %s", d.Action, tt.action)
+			}
+			var wouldAct Action
+			if d.WouldAct != nil {
+				wouldAct = d.WouldAct.Action
+			}
+			if wouldAct != tt.wouldAct {
+				t.Errorf("WouldAct = %q, want %q", wouldAct, tt.wouldAct)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package policy
+
+import (
+	"strconv"
+)
+
+// InvalidPolicy is returned when a policy document cannot be loaded.
+type InvalidPolicy struct {
+	Err error
+	Msg string
+}
+
+// Error returns the error message
+func (e *InvalidPolicy) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = "invalid policy"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// InvalidRule is returned when a rule definition is invalid.
+type InvalidRule struct {
+	Err  error
+	Name string
+	Msg  string
+}
+
+// Error returns the error message
+func (e *InvalidRule) Error() string {
+	msg := "invalid rule"
+	if e.Name != "" {
+		msg += " '" + e.Name + "'"
+	}
+	if e.Msg != "" {
+		msg += ": " + e.Msg
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// NoGeoIP is returned when the engine is created without a GeoIP instance.
+type NoGeoIP struct {
+	Err error
+	Msg string
+}
+
+// Error returns the error message
+func (e *NoGeoIP) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = "no geoip instance. use WithGeoIP()"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// InvalidIP is returned when the account IP address cannot be parsed.
+type InvalidIP struct {
+	Err error
+	IP  string
+	Msg string
+}
+
+// Error returns the error message
+func (e *InvalidIP) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = "invalid IP address"
+	}
+	if e.IP != "" {
+		msg += ": " + e.IP
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// LookupFailed is returned when the GeoIP lookup for an IP address fails.
+type LookupFailed struct {
+	Err error
+	IP  string
+	Msg string
+}
+
+// Error returns the error message
+func (e *LookupFailed) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = "geoip lookup failed"
+	}
+	if e.IP != "" {
+		msg += ": " + e.IP
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// RuleFailed is returned when a rule returns an error during evaluation.
+type RuleFailed struct {
+	Err  error
+	Rule string
+	Msg  string
+}
+
+// Error returns the error message
+func (e *RuleFailed) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = "rule evaluation failed"
+	}
+	if e.Rule != "" {
+		msg += ": " + e.Rule
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// InvalidExpression is returned when a rule expression fails to compile.
+type InvalidExpression struct {
+	Err  error
+	Expr string
+	Pos  int
+	Msg  string
+}
+
+// Error returns the error message
+func (e *InvalidExpression) Error() string {
+	msg := "invalid expression"
+	if e.Pos > 0 {
+		msg += " at position " + strconv.Itoa(e.Pos)
+	}
+	if e.Msg != "" {
+		msg += ": " + e.Msg
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Expr != "" {
+		msg += ": " + e.Expr
+	}
+	return msg
+}
+
+// VelocityFailed is returned when signups cannot be recorded or counted.
+type VelocityFailed struct {
+	Err error
+	Key string
+	Msg string
+}
+
+// Error returns the error message
+func (e *VelocityFailed) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = "velocity store failed"
+	}
+	if e.Key != "" {
+		msg += " for key '" + e.Key + "'"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
@@ -0,0 +1,719 @@
+package policy
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rmrfslashbin/mastoban/pkg/geoip"
+)
+
+// The expression language is a small, side effect free language for
+// composite conditions, e.g.
+//
+//	country not in ["US", "CA"] && (domain_in_list(email_domain, "disposable") || has_url(invite_request))
+//
+// Expressions are parsed and type checked when the policy is loaded, so a
+// policy with an invalid expression fails to load rather than failing at
+// evaluation time. Expressions cannot loop, call out, or modify anything.
+//
+// Operators, from lowest to highest precedence:
+//
+//	||
+//	&&
+//	== != < <= > >= in "not in"
+//	!
+//
+// Literals are double quoted strings, integers, true, false and lists of
+// strings or integers ([...]). The fields and functions available are
+// listed in exprFields and exprFuncs.
+
+// exprType is the static type of an expression.
+type exprType int
+
+const (
+	typeBool exprType = iota
+	typeInt
+	typeString
+	typeStringList
+	typeIntList
+)
+
+// String returns the name of the type.
+func (t exprType) String() string {
+	switch t {
+	case typeBool:
+		return "bool"
+	case typeInt:
+		return "int"
+	case typeString:
+		return "string"
+	case typeStringList:
+		return "list of strings"
+	case typeIntList:
+		return "list of ints"
+	}
+	return "unknown"
+}
+
+// exprField is a named value taken from the rule input.
+type exprField struct {
+	typ exprType
+	get func(in *Input) interface{}
+}
+
+// exprFields are the fields available to expressions.
+var exprFields = map[string]exprField{
+	"ip":             {typeString, func(in *Input) interface{} { return exprIP(in) }},
+	"ip_class":       {typeString, func(in *Input) interface{} { return string(in.IPClass) }},
+	"country":        {typeString, func(in *Input) interface{} { return exprGeoIP(in).Country }},
+	"continent":      {typeString, func(in *Input) interface{} { return exprGeoIP(in).Continent }},
+	"asn":            {typeInt, func(in *Input) interface{} { return int64(exprGeoIP(in).ASN) }},
+	"as_org":         {typeString, func(in *Input) interface{} { return exprGeoIP(in).ASOrganization }},
+	"username":       {typeString, func(in *Input) interface{} { return in.Event.Object.Username }},
+	"domain":         {typeString, func(in *Input) interface{} { return in.Event.Object.DomainName() }},
+	"email":          {typeString, func(in *Input) interface{} { return in.Event.Object.Email }},
+	"email_domain":   {typeString, func(in *Input) interface{} { return EmailDomain(in.Event.Object.Email) }},
+	"locale":         {typeString, func(in *Input) interface{} { return in.Event.Object.Locale }},
+	"invite_request": {typeString, func(in *Input) interface{} { return in.Event.Object.InviteRequest }},
+	"confirmed":      {typeBool, func(in *Input) interface{} { return in.Event.Object.Confirmed }},
+	"approved":       {typeBool, func(in *Input) interface{} { return in.Event.Object.Approved }},
+}
+
+// exprIP returns the account IP address, or "" if there is none.
+func exprIP(in *Input) string {
+	if in.IP == nil {
+		return ""
+	}
+	return in.IP.String()
+}
+
+// exprGeoIP returns the GeoIP data, or empty data if there was no lookup.
+// Fields missing from the lookup, e.g. the ASN without an ASN database,
+// are "" or 0.
+func exprGeoIP(in *Input) *geoip.GeoIPData {
+	if in.GeoIP == nil {
+		return &geoip.GeoIPData{}
+	}
+	return in.GeoIP
+}
+
+// exprFunc is a function callable from expressions.
+type exprFunc struct {
+	args []exprType
+	ret  exprType
+
+	// literal is the index of an argument that must be a string literal, or -1.
+	// compile is called with the literal to prepare it, e.g. compile a regex.
+	literal int
+	compile func(c *exprCompiler, literal string) (interface{}, error)
+
+	call func(args []interface{}, compiled interface{}) interface{}
+}
+
+// exprFuncs are the functions available to expressions.
+var exprFuncs = map[string]*exprFunc{
+	"contains": {args: []exprType{typeString, typeString}, ret: typeBool, literal: -1,
+		call: func(args []interface{}, _ interface{}) interface{} {
+			return strings.Contains(args[0].(string), args[1].(string))
+		}},
+	"starts_with": {args: []exprType{typeString, typeString}, ret: typeBool, literal: -1,
+		call: func(args []interface{}, _ interface{}) interface{} {
+			return strings.HasPrefix(args[0].(string), args[1].(string))
+		}},
+	"ends_with": {args: []exprType{typeString, typeString}, ret: typeBool, literal: -1,
+		call: func(args []interface{}, _ interface{}) interface{} {
+			return strings.HasSuffix(args[0].(string), args[1].(string))
+		}},
+	"lower": {args: []exprType{typeString}, ret: typeString, literal: -1,
+		call: func(args []interface{}, _ interface{}) interface{} {
+			return strings.ToLower(args[0].(string))
+		}},
+	"len": {args: []exprType{typeString}, ret: typeInt, literal: -1,
+		call: func(args []interface{}, _ interface{}) interface{} {
+			return int64(utf8.RuneCountInString(strings.TrimSpace(args[0].(string))))
+		}},
+	"has_url": {args: []exprType{typeString}, ret: typeBool, literal: -1,
+		call: func(args []interface{}, _ interface{}) interface{} {
+			return urlPattern.MatchString(args[0].(string))
+		}},
+	"matches": {args: []exprType{typeString, typeString}, ret: typeBool, literal: 1,
+		compile: func(_ *exprCompiler, literal string) (interface{}, error) {
+			return regexp.Compile(literal)
+		},
+		call: func(args []interface{}, compiled interface{}) interface{} {
+			return compiled.(*regexp.Regexp).MatchString(args[0].(string))
+		}},
+	"in_cidr": {args: []exprType{typeString, typeString}, ret: typeBool, literal: 1,
+		compile: func(_ *exprCompiler, literal string) (interface{}, error) {
+			return parsePrefix(literal)
+		},
+		call: func(args []interface{}, compiled interface{}) interface{} {
+			ip := net.ParseIP(args[0].(string))
+			return ip != nil && compiled.(*net.IPNet).Contains(ip)
+		}},
+	"in_list": {args: []exprType{typeString, typeString}, ret: typeBool, literal: 1,
+		compile: func(c *exprCompiler, literal string) (interface{}, error) {
+			items, err := c.list(literal)
+			if err != nil {
+				return nil, err
+			}
+			set := make(map[string]struct{})
+			for _, item := range items {
+				set[strings.ToLower(strings.TrimSpace(item))] = struct{}{}
+			}
+			return set, nil
+		},
+		call: func(args []interface{}, compiled interface{}) interface{} {
+			_, ok := compiled.(map[string]struct{})[strings.ToLower(args[0].(string))]
+			return ok
+		}},
+	"domain_in_list": {args: []exprType{typeString, typeString}, ret: typeBool, literal: 1,
+		compile: func(c *exprCompiler, literal string) (interface{}, error) {
+			items, err := c.list(literal)
+			if err != nil {
+				return nil, err
+			}
+			return newDomainMatcher(items, "", true)
+		},
+		call: func(args []interface{}, compiled interface{}) interface{} {
+			domain := strings.TrimSuffix(strings.ToLower(args[0].(string)), ".")
+			return domain != "" && compiled.(*domainMatcher).match(domain) != ""
+		}},
+}
+
+// Expression is a compiled, type checked expression.
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// String returns the source of the expression.
+func (x *Expression) String() string {
+	return x.source
+}
+
+// Eval evaluates the expression against the rule input.
+func (x *Expression) Eval(in *Input) bool {
+	return x.root.eval(in).(bool)
+}
+
+// CompileExpression parses and type checks a boolean expression. lists
+// are the named lists available to in_list() and domain_in_list().
+func CompileExpression(source string, lists map[string][]string) (*Expression, error) {
+	c := &exprCompiler{source: source, lists: lists}
+	if err := c.lex(); err != nil {
+		return nil, err
+	}
+
+	root, err := c.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := c.peek(); tok.kind != tokEOF {
+		return nil, c.errorAt(tok, "unexpected '"+tok.text+"'")
+	}
+	if root.typ() != typeBool {
+		return nil, &InvalidExpression{Expr: source, Msg: "expression is " + root.typ().String() + ", not bool"}
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// exprNode is a node in the expression tree.
+type exprNode interface {
+	typ() exprType
+	eval(in *Input) interface{}
+}
+
+type literalNode struct {
+	t exprType
+	v interface{}
+}
+
+func (n *literalNode) typ() exprType              { return n.t }
+func (n *literalNode) eval(in *Input) interface{} { return n.v }
+
+type fieldNode struct {
+	field exprField
+}
+
+func (n *fieldNode) typ() exprType              { return n.field.typ }
+func (n *fieldNode) eval(in *Input) interface{} { return n.field.get(in) }
+
+type notNode struct {
+	x exprNode
+}
+
+func (n *notNode) typ() exprType              { return typeBool }
+func (n *notNode) eval(in *Input) interface{} { return !n.x.eval(in).(bool) }
+
+type logicNode struct {
+	and  bool
+	l, r exprNode
+}
+
+func (n *logicNode) typ() exprType { return typeBool }
+func (n *logicNode) eval(in *Input) interface{} {
+	l := n.l.eval(in).(bool)
+	if n.and {
+		return l && n.r.eval(in).(bool)
+	}
+	return l || n.r.eval(in).(bool)
+}
+
+type compareNode struct {
+	op   string
+	l, r exprNode
+}
+
+func (n *compareNode) typ() exprType { return typeBool }
+func (n *compareNode) eval(in *Input) interface{} {
+	l, r := n.l.eval(in), n.r.eval(in)
+	switch n.op {
+	case "==":
+		return l == r
+	case "!=":
+		return l != r
+	case "<":
+		return l.(int64) < r.(int64)
+	case "<=":
+		return l.(int64) <= r.(int64)
+	case ">":
+		return l.(int64) > r.(int64)
+	case ">=":
+		return l.(int64) >= r.(int64)
+	}
+	return false
+}
+
+type inNode struct {
+	not  bool
+	l, r exprNode
+}
+
+func (n *inNode) typ() exprType { return typeBool }
+func (n *inNode) eval(in *Input) interface{} {
+	l := n.l.eval(in)
+	found := false
+	switch list := n.r.eval(in).(type) {
+	case []string:
+		for _, item := range list {
+			if item == l {
+				found = true
+				break
+			}
+		}
+	case []int64:
+		for _, item := range list {
+			if item == l {
+				found = true
+				break
+			}
+		}
+	}
+	return found != n.not
+}
+
+type listNode struct {
+	t     exprType
+	items []exprNode
+}
+
+func (n *listNode) typ() exprType { return n.t }
+func (n *listNode) eval(in *Input) interface{} {
+	if n.t == typeIntList {
+		list := make([]int64, len(n.items))
+		for i, item := range n.items {
+			list[i] = item.eval(in).(int64)
+		}
+		return list
+	}
+	list := make([]string, len(n.items))
+	for i, item := range n.items {
+		list[i] = item.eval(in).(string)
+	}
+	return list
+}
+
+type callNode struct {
+	fn       *exprFunc
+	args     []exprNode
+	compiled interface{}
+}
+
+func (n *callNode) typ() exprType { return n.fn.ret }
+func (n *callNode) eval(in *Input) interface{} {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.eval(in)
+	}
+	return n.fn.call(args, n.compiled)
+}
+
+// Tokens
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokInt
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// exprCompiler holds the state used to lex, parse and type check an expression.
+type exprCompiler struct {
+	source string
+	lists  map[string][]string
+	tokens []token
+	next   int
+}
+
+// list returns the named list.
+func (c *exprCompiler) list(name string) ([]string, error) {
+	items, ok := c.lists[name]
+	if !ok {
+		return nil, errors.New("unknown list '" + name + "'")
+	}
+	return items, nil
+}
+
+// errorAt returns an InvalidExpression error for the given token.
+func (c *exprCompiler) errorAt(tok token, msg string) error {
+	return &InvalidExpression{Expr: c.source, Pos: tok.pos + 1, Msg: msg}
+}
+
+// lex splits the source into tokens.
+func (c *exprCompiler) lex() error {
+	src := c.source
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(src) {
+				r, size := utf8.DecodeRuneInString(src[i:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+			c.tokens = append(c.tokens, token{tokIdent, src[start:i], start})
+
+		case r >= '0' && r <= '9':
+			start := i
+			for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+				i++
+			}
+			c.tokens = append(c.tokens, token{tokInt, src[start:i], start})
+
+		case r == '"':
+			start := i
+			i++
+			for i < len(src) && src[i] != '"' {
+				if src[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(src) {
+				return &InvalidExpression{Expr: src, Pos: start + 1, Msg: "unterminated string"}
+			}
+			i++
+			text, err := strconv.Unquote(src[start:i])
+			if err != nil {
+				return &InvalidExpression{Expr: src, Pos: start + 1, Msg: "invalid string", Err: err}
+			}
+			c.tokens = append(c.tokens, token{tokString, text, start})
+
+		default:
+			start := i
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return &InvalidExpression{Expr: src, Pos: start + 1, Msg: "unexpected character '" + string(r) + "'"}
+			}
+			i += len(op)
+			c.tokens = append(c.tokens, token{tokOp, op, start})
+		}
+	}
+	c.tokens = append(c.tokens, token{tokEOF, "end of expression", len(src)})
+	return nil
+}
+
+// peek returns the next token without consuming it.
+func (c *exprCompiler) peek() token {
+	return c.tokens[c.next]
+}
+
+// take consumes and returns the next token.
+func (c *exprCompiler) take() token {
+	tok := c.tokens[c.next]
+	if tok.kind != tokEOF {
+		c.next++
+	}
+	return tok
+}
+
+// isOp reports whether the next token is the given operator or keyword.
+func (c *exprCompiler) isOp(op string) bool {
+	tok := c.peek()
+	return (tok.kind == tokOp || tok.kind == tokIdent) && tok.text == op
+}
+
+// expect consumes the given operator or returns an error.
+func (c *exprCompiler) expect(op string) error {
+	if !c.isOp(op) {
+		tok := c.peek()
+		return c.errorAt(tok, "expected '"+op+"', found '"+tok.text+"'")
+	}
+	c.take()
+	return nil
+}
+
+// parseOr parses: and ('||' and)*
+func (c *exprCompiler) parseOr() (exprNode, error) {
+	l, err := c.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for c.isOp("||") {
+		tok := c.take()
+		r, err := c.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if l.typ() != typeBool || r.typ() != typeBool {
+			return nil, c.errorAt(tok, "'||' needs bool operands")
+		}
+		l = &logicNode{and: false, l: l, r: r}
+	}
+	return l, nil
+}
+
+// parseAnd parses: compare ('&&' compare)*
+func (c *exprCompiler) parseAnd() (exprNode, error) {
+	l, err := c.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for c.isOp("&&") {
+		tok := c.take()
+		r, err := c.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		if l.typ() != typeBool || r.typ() != typeBool {
+			return nil, c.errorAt(tok, "'&&' needs bool operands")
+		}
+		l = &logicNode{and: true, l: l, r: r}
+	}
+	return l, nil
+}
+
+// parseCompare parses: unary (op unary)?
+func (c *exprCompiler) parseCompare() (exprNode, error) {
+	l, err := c.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := c.peek()
+	switch {
+	case c.isOp("in") || c.isOp("not"):
+		not := c.take().text == "not"
+		if not {
+			if err := c.expect("in"); err != nil {
+				return nil, err
+			}
+		}
+		r, err := c.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if !(l.typ() == typeString && r.typ() == typeStringList) && !(l.typ() == typeInt && r.typ() == typeIntList) {
+			return nil, c.errorAt(tok, "cannot check if "+l.typ().String()+" is in "+r.typ().String())
+		}
+		return &inNode{not: not, l: l, r: r}, nil
+
+	case tok.kind == tokOp && (tok.text == "==" || tok.text == "!="):
+		c.take()
+		r, err := c.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if l.typ() != r.typ() || l.typ() == typeStringList || l.typ() == typeIntList {
+			return nil, c.errorAt(tok, "cannot compare "+l.typ().String()+" with "+r.typ().String())
+		}
+		return &compareNode{op: tok.text, l: l, r: r}, nil
+
+	case tok.kind == tokOp && (tok.text == "<" || tok.text == "<=" || tok.text == ">" || tok.text == ">="):
+		c.take()
+		r, err := c.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if l.typ() != typeInt || r.typ() != typeInt {
+			return nil, c.errorAt(tok, "'"+tok.text+"' needs int operands")
+		}
+		return &compareNode{op: tok.text, l: l, r: r}, nil
+	}
+	return l, nil
+}
+
+// parseUnary parses: '!' unary | primary
+func (c *exprCompiler) parseUnary() (exprNode, error) {
+	if c.isOp("!") {
+		tok := c.take()
+		x, err := c.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if x.typ() != typeBool {
+			return nil, c.errorAt(tok, "'!' needs a bool operand")
+		}
+		return &notNode{x: x}, nil
+	}
+	return c.parsePrimary()
+}
+
+// parsePrimary parses literals, fields, function calls, lists and parentheses.
+func (c *exprCompiler) parsePrimary() (exprNode, error) {
+	tok := c.take()
+	switch tok.kind {
+	case tokString:
+		return &literalNode{t: typeString, v: tok.text}, nil
+
+	case tokInt:
+		v, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return nil, c.errorAt(tok, "invalid integer '"+tok.text+"'")
+		}
+		return &literalNode{t: typeInt, v: v}, nil
+
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{t: typeBool, v: true}, nil
+		case "false":
+			return &literalNode{t: typeBool, v: false}, nil
+		}
+		if c.isOp("(") {
+			return c.parseCall(tok)
+		}
+		field, ok := exprFields[tok.text]
+		if !ok {
+			return nil, c.errorAt(tok, "unknown field '"+tok.text+"'")
+		}
+		return &fieldNode{field: field}, nil
+
+	case tokOp:
+		switch tok.text {
+		case "(":
+			x, err := c.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := c.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		case "[":
+			return c.parseList(tok)
+		}
+	}
+	return nil, c.errorAt(tok, "unexpected '"+tok.text+"'")
+}
+
+// parseCall parses the arguments of a function call and type checks them.
+func (c *exprCompiler) parseCall(name token) (exprNode, error) {
+	fn, ok := exprFuncs[name.text]
+	if !ok {
+		return nil, c.errorAt(name, "unknown function '"+name.text+"'")
+	}
+	c.take() // (
+
+	n := &callNode{fn: fn}
+	for !c.isOp(")") {
+		if len(n.args) > 0 {
+			if err := c.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := c.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		n.args = append(n.args, arg)
+	}
+	c.take() // )
+
+	if len(n.args) != len(fn.args) {
+		return nil, c.errorAt(name, name.text+"() takes "+strconv.Itoa(len(fn.args))+" arguments, got "+strconv.Itoa(len(n.args)))
+	}
+	for i, arg := range n.args {
+		if arg.typ() != fn.args[i] {
+			return nil, c.errorAt(name, name.text+"() argument "+strconv.Itoa(i+1)+" must be "+fn.args[i].String()+", got "+arg.typ().String())
+		}
+	}
+
+	if fn.literal >= 0 {
+		lit, ok := n.args[fn.literal].(*literalNode)
+		if !ok {
+			return nil, c.errorAt(name, name.text+"() argument "+strconv.Itoa(fn.literal+1)+" must be a string literal")
+		}
+		compiled, err := fn.compile(c, lit.v.(string))
+		if err != nil {
+			return nil, &InvalidExpression{Expr: c.source, Pos: name.pos + 1, Msg: name.text + "()", Err: err}
+		}
+		n.compiled = compiled
+	}
+	return n, nil
+}
+
+// parseList parses a list literal of strings or ints.
+func (c *exprCompiler) parseList(open token) (exprNode, error) {
+	n := &listNode{t: typeStringList}
+	for !c.isOp("]") {
+		if len(n.items) > 0 {
+			if err := c.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		tok := c.peek()
+		item, err := c.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case len(n.items) == 0 && item.typ() == typeInt:
+			n.t = typeIntList
+		case n.t == typeStringList && item.typ() == typeString:
+		case n.t == typeIntList && item.typ() == typeInt:
+		default:
+			return nil, c.errorAt(tok, "list items must all be strings or all be ints")
+		}
+		n.items = append(n.items, item)
+	}
+	c.take() // ]
+	return n, nil
+}
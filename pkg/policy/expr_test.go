@@ -0,0 +1,231 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/mastoban/pkg/geoip"
+)
+
+// exprTestLists are the named lists available to the test expressions.
+var exprTestLists = map[string][]string{
+	"staff":      {"Alice", "bob"},
+	"disposable": {"mailinator.com"},
+}
+
+func TestExpressionEval(t *testing.T) {
+	in := newTestInput("1", "192.0.2.1")
+	in.Event.Object.Username = "alice"
+	in.Event.Object.Email = "alice@mailinator.com"
+	in.Event.Object.InviteRequest = "see https://spam.example"
+	in.Event.Object.Approved = true
+
+	tests := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		// Precedence
+		{"and binds tighter than or", `true || false && false`, true},
+		{"and binds tighter than or, reversed", `false && true || true`, true},
+		{"parentheses override precedence", `(true || false) && false`, false},
+		{"not binds tighter than and", `!confirmed && approved`, true},
+		{"not of a group", `!(confirmed || approved)`, false},
+		{"double not", `!!approved`, true},
+		{"comparison binds tighter than and", `len(username) > 3 && country == "US"`, true},
+		{"comparison binds tighter than or", `country == "CA" || asn == 14061`, true},
+		{"not of a comparison", `!(len(username) > 10)`, true},
+
+		// Comparisons
+		{"string equal", `username == "alice"`, true},
+		{"string not equal", `username != "alice"`, false},
+		{"bool equal", `confirmed == false`, true},
+		{"int less than", `len(username) < 5`, false},
+		{"int less or equal", `len(username) <= 5`, true},
+		{"int greater than", `asn > 14060`, true},
+		{"int greater or equal", `asn >= 14062`, false},
+
+		// in and not in
+		{"in string list", `country in ["US", "CA"]`, true},
+		{"in string list, no match", `country in ["DE", "FR"]`, false},
+		{"not in string list", `country not in ["US", "CA"]`, false},
+		{"not in string list, no match", `country not in ["DE"]`, true},
+		{"in int list", `asn in [16509, 14061]`, true},
+		{"not in int list", `asn not in [16509]`, true},
+		{"in empty list", `country in []`, false},
+		{"not in empty list", `country not in []`, true},
+		{"in list of fields", `"NA" in [country, continent]`, true},
+		{"in is case sensitive", `country in ["us"]`, false},
+		{"in combined with and", `country not in ["DE"] && asn in [14061]`, true},
+
+		// Functions
+		{"contains", `contains(invite_request, "https://")`, true},
+		{"starts_with", `starts_with(email, "alice@")`, true},
+		{"ends_with", `ends_with(email, ".org")`, false},
+		{"lower", `lower("ALICE") == username`, true},
+		{"has_url", `has_url(invite_request)`, true},
+		{"matches", `matches(username, "^al")`, true},
+		{"matches, no match", `matches(username, "^bob$")`, false},
+		{"in_cidr", `in_cidr(ip, "192.0.2.0/24")`, true},
+		{"in_cidr, no match", `in_cidr(ip, "10.0.0.0/8")`, false},
+		{"in_cidr bare address", `in_cidr(ip, "192.0.2.1")`, true},
+		{"in_list is case insensitive", `in_list(username, "staff")`, true},
+		{"in_list, no match", `in_list(locale, "staff")`, false},
+		{"domain_in_list", `domain_in_list(email_domain, "disposable")`, true},
+		{"domain_in_list, no match", `domain_in_list(domain, "disposable")`, false},
+
+		// Fields
+		{"ip", `ip == "192.0.2.1"`, true},
+		{"ip_class of a public address", `ip_class == ""`, true},
+		{"as_org", `as_org == "DIGITALOCEAN"`, true},
+		{"email_domain", `email_domain == "mailinator.com"`, true},
+		{"locale", `locale == "en"`, true},
+		{"domain of a local account", `domain == ""`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := CompileExpression(tt.expr, exprTestLists)
+			if err != nil {
+				t.Fatalf("CompileExpression(%s): %v", tt.expr, err)
+			}
+			if got := x.Eval(in); got != tt.want {
+				t.Errorf("Eval(%s) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		pos  int
+		msg  string
+	}{
+		// Chained comparisons
+		{"chained comparison", `len(username) > 3 == true`, 19, "unexpected '=='"},
+		{"chained equality", `country == "US" == true`, 17, "unexpected '=='"},
+		{"chained in", `country in ["US"] in ["x"]`, 19, "unexpected 'in'"},
+
+		// Precedence
+		{"not binds tighter than comparison", `!len(username) > 3`, 1, "'!' needs a bool operand"},
+
+		// Type errors
+		{"string compared with int", `username == 1`, 10, "cannot compare string with int"},
+		{"int compared with string", `asn == "14061"`, 5, "cannot compare int with string"},
+		{"bool compared with string", `confirmed != "true"`, 11, "cannot compare bool with string"},
+		{"lists compared", `["a"] == ["a"]`, 7, "cannot compare list of strings with list of strings"},
+		{"ordering strings", `username < "b"`, 10, "'<' needs int operands"},
+		{"and of strings", `country && true`, 9, "'&&' needs bool operands"},
+		{"or of ints", `false || asn`, 7, "'||' needs bool operands"},
+		{"not of a string", `!country`, 1, "'!' needs a bool operand"},
+		{"not bool", `username`, 0, "expression is string, not bool"},
+		{"int expression", `asn`, 0, "expression is int, not bool"},
+
+		// in and not in
+		{"mixed list, string first", `country in ["US", 1]`, 19, "list items must all be strings or all be ints"},
+		{"mixed list, int first", `asn in [1, "US"]`, 12, "list items must all be strings or all be ints"},
+		{"string in int list", `country in [14061]`, 9, "cannot check if string is in list of ints"},
+		{"int in string list", `asn in ["US"]`, 5, "cannot check if int is in list of strings"},
+		{"int in empty list", `asn in []`, 5, "cannot check if int is in list of strings"},
+		{"in a string", `username in country`, 10, "cannot check if string is in string"},
+		{"not without in", `country not ["US"]`, 13, "expected 'in', found '['"},
+		{"nested list", `country in [["US"]]`, 13, "list items must all be strings or all be ints"},
+
+		// Literal arguments
+		{"matches with a field", `matches(username, email)`, 1, "matches() argument 2 must be a string literal"},
+		{"matches with an expression", `matches(username, lower("^A"))`, 1, "matches() argument 2 must be a string literal"},
+		{"matches with an invalid regex", `matches(username, "(")`, 1, "matches(): error parsing regexp"},
+		{"in_cidr with a field", `in_cidr(ip, username)`, 1, "in_cidr() argument 2 must be a string literal"},
+		{"in_cidr with an invalid prefix", `in_cidr(ip, "10.0.0.0/33")`, 1, "in_cidr(): invalid CIDR prefix"},
+		{"in_list with a field", `in_list(username, locale)`, 1, "in_list() argument 2 must be a string literal"},
+		{"in_list with an unknown list", `in_list(username, "missing")`, 1, "in_list(): unknown list 'missing'"},
+		{"domain_in_list with a field", `domain_in_list(email_domain, email)`, 1, "domain_in_list() argument 2 must be a string literal"},
+
+		// Calls
+		{"unknown function", `exec(username)`, 1, "unknown function 'exec'"},
+		{"too many arguments", `len(username, email)`, 1, "len() takes 1 arguments, got 2"},
+		{"too few arguments", `contains(username)`, 1, "contains() takes 2 arguments, got 1"},
+		{"wrong argument type", `len(asn) > 1`, 1, "len() argument 1 must be string, got int"},
+		{"missing comma", `contains(username "a")`, 19, "expected ',', found 'a'"},
+
+		// Syntax
+		{"unknown field", `country == "US" && user == "x"`, 20, "unknown field 'user'"},
+		{"unterminated string", `username == "alice`, 13, "unterminated string"},
+		{"unexpected character", `country == "US" # comment`, 17, "unexpected character '#'"},
+		{"single ampersand", `true & false`, 6, "unexpected character '&'"},
+		{"unclosed parenthesis", `(true || false`, 15, "expected ')', found 'end of expression'"},
+		{"unclosed list", `country in ["US"`, 17, "expected ',', found 'end of expression'"},
+		{"missing operand", `username == `, 13, "unexpected 'end of expression'"},
+		{"trailing parenthesis", `true)`, 5, "unexpected ')'"},
+		{"empty", ``, 1, "unexpected 'end of expression'"},
+		{"position counts bytes", `"é" == 1`, 6, "cannot compare string with int"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := CompileExpression(tt.expr, exprTestLists)
+			if err == nil {
+				t.Fatalf("CompileExpression(%s) = %s, want error", tt.expr, x)
+			}
+
+			var invalid *InvalidExpression
+			if !errors.As(err, &invalid) {
+				t.Fatalf("CompileExpression(%s) error = %T, want *InvalidExpression", tt.expr, err)
+			}
+			if invalid.Pos != tt.pos {
+				t.Errorf("CompileExpression(%s) position = %d, want %d (%s)", tt.expr, invalid.Pos, tt.pos, err)
+			}
+			if !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("CompileExpression(%s) error = %q, want it to contain %q", tt.expr, err, tt.msg)
+			}
+		})
+	}
+}
+
+func TestExpressionMissingData(t *testing.T) {
+	noGeoIP := newTestInput("1", "192.0.2.1")
+	noGeoIP.GeoIP = nil
+
+	noIP := newTestInput("1", "")
+	noIP.GeoIP = nil
+
+	noASN := newTestInput("1", "192.0.2.1")
+	noASN.GeoIP = &geoip.GeoIPData{IP: noASN.IP, Continent: "NA", Country: "US"}
+
+	tests := []struct {
+		name string
+		in   *Input
+		expr string
+		want bool
+	}{
+		{"no geoip, country", noGeoIP, `country == ""`, true},
+		{"no geoip, continent", noGeoIP, `continent == ""`, true},
+		{"no geoip, asn", noGeoIP, `asn == 0`, true},
+		{"no geoip, as_org", noGeoIP, `as_org == ""`, true},
+		{"no geoip, country in", noGeoIP, `country in ["US", "CA"]`, false},
+		{"no geoip, country not in", noGeoIP, `country not in ["US", "CA"]`, true},
+		{"no geoip, ip still set", noGeoIP, `in_cidr(ip, "192.0.2.0/24")`, true},
+		{"no ip", noIP, `ip == ""`, true},
+		{"no ip, in_cidr", noIP, `in_cidr(ip, "0.0.0.0/0")`, false},
+		{"no ip, in_cidr ipv6", noIP, `in_cidr(ip, "::/0")`, false},
+		{"no asn database, asn", noASN, `asn == 0`, true},
+		{"no asn database, asn in", noASN, `asn in [14061]`, false},
+		{"no asn database, as_org", noASN, `contains(as_org, "OCEAN")`, false},
+		{"no asn database, country", noASN, `country == "US"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := CompileExpression(tt.expr, exprTestLists)
+			if err != nil {
+				t.Fatalf("CompileExpression(%s): %v", tt.expr, err)
+			}
+			if got := x.Eval(tt.in); got != tt.want {
+				t.Errorf("Eval(%s) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
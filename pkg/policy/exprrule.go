@@ -0,0 +1,57 @@
+package policy
+
+// ExprParams configures an ExprRule.
+type ExprParams struct {
+	// Condition is the expression to evaluate. The rule acts on the account when it is true.
+	Condition string `json:"condition"`
+
+	// Reason is logged with the decision. Defaults to the condition.
+	Reason string `json:"reason"`
+}
+
+// ExprRule acts on accounts matching an expression.
+type ExprRule struct {
+	name   string
+	expr   *Expression
+	reason string
+}
+
+// NewExprRule creates a new ExprRule. lists are the named lists
+// available to the expression.
+func NewExprRule(name string, params *ExprParams, lists map[string][]string) (*ExprRule, error) {
+	if params.Condition == "" {
+		return nil, &InvalidRule{Name: name, Msg: "condition is empty"}
+	}
+
+	expr, err := CompileExpression(params.Condition, lists)
+	if err != nil {
+		return nil, &InvalidRule{Name: name, Err: err}
+	}
+
+	r := &ExprRule{
+		name:   name,
+		expr:   expr,
+		reason: params.Reason,
+	}
+	if r.reason == "" {
+		r.reason = "condition matched: " + expr.String()
+	}
+	return r, nil
+}
+
+// Name returns the name of the rule.
+func (r *ExprRule) Name() string {
+	return r.name
+}
+
+// Evaluate acts on accounts for which the condition is true.
+func (r *ExprRule) Evaluate(in *Input) (*Result, error) {
+	if !r.expr.Eval(in) {
+		return nil, nil
+	}
+	return &Result{
+		Action: ActionAct,
+		Reason: r.reason,
+		Match:  r.expr.String(),
+	}, nil
+}
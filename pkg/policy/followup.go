@@ -0,0 +1,92 @@
+package policy
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/mastoclient"
+)
+
+// FollowUpParams schedules a follow-up action for accounts the rule acts
+// on. The follow-up either escalates to another suspend level, or lifts
+// the rule's level, after a delay. It is dropped if a moderator clears or
+// changes the account's level in the meantime.
+type FollowUpParams struct {
+	// After is how long to wait, e.g. "24h".
+	After string `json:"after"`
+
+	// Level is the suspend level to escalate to, e.g. "suspend".
+	Level string `json:"level,omitempty"`
+
+	// Lift reverses the rule's suspend level instead, e.g. to
+	// end a probation period. Exactly one of Level and Lift is set.
+	Lift bool `json:"lift,omitempty"`
+
+	// after is the parsed After.
+	after time.Duration
+}
+
+// FollowUp is the follow-up action to schedule for a decision.
+type FollowUp struct {
+	// After is how long to wait before running the follow-up.
+	After time.Duration
+
+	// Level is the suspend level to escalate to. It is empty when Lift is set.
+	Level string
+
+	// Lift reverses the decision's suspend level.
+	Lift bool
+}
+
+// String returns the follow-up as "<level> after <delay>" or "lift after <delay>".
+func (f *FollowUp) String() string {
+	action := f.Level
+	if f.Lift {
+		action = "lift"
+	}
+	return action + " after " + f.After.String()
+}
+
+// validate checks the follow-up parameters for a rule with the given
+// action and level. The follow-up only runs while the account has the
+// rule's level, so the rule must act on the account.
+func (p *FollowUpParams) validate(name string, action Action, level string) error {
+	if (action != "" && action != ActionAct) || strings.EqualFold(level, "none") {
+		return &InvalidRule{Name: name, Msg: "follow_up needs a rule that acts on the account"}
+	}
+
+	after, err := time.ParseDuration(p.After)
+	if err != nil {
+		return &InvalidRule{Name: name, Msg: "invalid follow_up after", Err: err}
+	}
+	if after <= 0 {
+		return &InvalidRule{Name: name, Msg: "follow_up after must be greater than zero"}
+	}
+	p.after = after
+
+	switch {
+	case p.Lift && p.Level != "":
+		return &InvalidRule{Name: name, Msg: "follow_up cannot set both level and lift"}
+	case !p.Lift && p.Level == "":
+		return &InvalidRule{Name: name, Msg: "follow_up needs a level or lift"}
+	case p.Level == "none" || (p.Level != "" && !mastoclient.ValidSuspendLevel(p.Level)):
+		return &InvalidRule{Name: name, Msg: "invalid follow_up level '" + p.Level + "'"}
+	}
+	return nil
+}
+
+// followUp returns the follow-up action for a decision.
+func (p *FollowUpParams) followUp() *FollowUp {
+	return &FollowUp{After: p.after, Level: p.Level, Lift: p.Lift}
+}
+
+// SchedulesFollowUps reports whether any rule in the policy schedules
+// follow-up actions, which need a store the scheduler can read.
+func (p *Policy) SchedulesFollowUps() bool {
+	for i := range p.Rules {
+		if p.Rules[i].FollowUp != nil {
+			return true
+		}
+	}
+	return false
+}
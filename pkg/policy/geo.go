@@ -0,0 +1,131 @@
+package policy
+
+import (
+	"strings"
+)
+
+// GeoParams configures a GeoRule. Country codes are ISO 3166-1 alpha-2,
+// continent codes are the two letter codes used by GeoIP (AF, AN, AS, EU, NA, OC, SA).
+//
+// Lists are checked in this order, and the first match wins:
+//  1. DenyCountries
+//  2. PermitCountries
+//  3. DenyContinents
+//  4. PermitContinents
+//
+// Country lists are exceptions to the continent lists, and deny lists take
+// precedence over permit lists at the same level. When a permit list is set,
+// accounts that match none of the lists are acted on; otherwise they are permitted.
+type GeoParams struct {
+	// PermitCountries lists the permitted countries.
+	PermitCountries []string `json:"permit_countries"`
+
+	// DenyCountries lists countries to act on.
+	DenyCountries []string `json:"deny_countries"`
+
+	// PermitContinents lists the permitted continents.
+	PermitContinents []string `json:"permit_continents"`
+
+	// DenyContinents lists continents to act on.
+	DenyContinents []string `json:"deny_continents"`
+}
+
+// GeoRule acts on accounts based on the GeoIP country and continent of their IP address.
+type GeoRule struct {
+	name             string
+	permitCountries  map[string]struct{}
+	denyCountries    map[string]struct{}
+	permitContinents map[string]struct{}
+	denyContinents   map[string]struct{}
+}
+
+// NewGeoRule creates a new GeoRule.
+func NewGeoRule(name string, params *GeoParams) (*GeoRule, error) {
+	r := &GeoRule{
+		name:             name,
+		permitCountries:  codeSet(params.PermitCountries),
+		denyCountries:    codeSet(params.DenyCountries),
+		permitContinents: codeSet(params.PermitContinents),
+		denyContinents:   codeSet(params.DenyContinents),
+	}
+	if len(r.permitCountries) == 0 && len(r.denyCountries) == 0 &&
+		len(r.permitContinents) == 0 && len(r.denyContinents) == 0 {
+		return nil, &InvalidRule{Name: name, Msg: "all country and continent lists are empty"}
+	}
+	return r, nil
+}
+
+// NewGeoPolicy builds a single rule policy from a set of geo lists.
+func NewGeoPolicy(name string, params *GeoParams) *Policy {
+	return &Policy{
+		Name: name,
+		Rules: []RuleDefinition{{
+			Name: "geo",
+			Geo:  params,
+		}},
+	}
+}
+
+// Name returns the name of the rule.
+func (r *GeoRule) Name() string {
+	return r.name
+}
+
+// Evaluate acts on accounts from a denied country or continent, or from
+// outside the permitted countries and continents when a permit list is set.
+func (r *GeoRule) Evaluate(in *Input) (*Result, error) {
+	country := in.GeoIP.Country
+	continent := in.GeoIP.Continent
+
+	if _, ok := r.denyCountries[country]; ok {
+		return &Result{
+			Action: ActionAct,
+			Reason: "country '" + country + "' is in the deny list",
+		}, nil
+	}
+
+	if _, ok := r.permitCountries[country]; ok {
+		return nil, nil
+	}
+
+	if _, ok := r.denyContinents[continent]; ok {
+		return &Result{
+			Action: ActionAct,
+			Reason: "continent '" + continent + "' is in the deny list",
+		}, nil
+	}
+
+	if _, ok := r.permitContinents[continent]; ok {
+		return nil, nil
+	}
+
+	if len(r.permitCountries) == 0 && len(r.permitContinents) == 0 {
+		return nil, nil
+	}
+	return &Result{
+		Action: ActionAct,
+		Reason: "country '" + country + "' (continent '" + continent + "') is not in the permit lists",
+	}, nil
+}
+
+// ParseList splits a comma separated list, trimming whitespace and dropping empty items.
+func ParseList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// codeSet builds an upper-cased lookup set from a list of codes.
+func codeSet(codes []string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, code := range codes {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			set[code] = struct{}{}
+		}
+	}
+	return set
+}
@@ -0,0 +1,76 @@
+package policy
+
+import "testing"
+
+func TestGeoRuleEvaluate(t *testing.T) {
+	tests := []struct {
+		name      string
+		params    *GeoParams
+		country   string
+		continent string
+		want      bool
+	}{
+		// Country lists
+		{"denied country", &GeoParams{DenyCountries: []string{"RU"}}, "RU", "EU", true},
+		{"country not denied", &GeoParams{DenyCountries: []string{"RU"}}, "US", "NA", false},
+		{"permitted country", &GeoParams{PermitCountries: []string{"US"}}, "US", "NA", false},
+		{"country not permitted", &GeoParams{PermitCountries: []string{"US"}}, "CA", "NA", true},
+		{"deny before permit", &GeoParams{PermitCountries: []string{"US"}, DenyCountries: []string{"US"}}, "US", "NA", true},
+		{"lower case codes", &GeoParams{DenyCountries: []string{" ru "}}, "RU", "EU", true},
+
+		// Continent lists
+		{"denied continent", &GeoParams{DenyContinents: []string{"AS"}}, "CN", "AS", true},
+		{"continent not denied", &GeoParams{DenyContinents: []string{"AS"}}, "DE", "EU", false},
+		{"permitted continent", &GeoParams{PermitContinents: []string{"EU"}}, "DE", "EU", false},
+		{"continent not permitted", &GeoParams{PermitContinents: []string{"EU"}}, "US", "NA", true},
+		{"continent deny before permit", &GeoParams{PermitContinents: []string{"EU"}, DenyContinents: []string{"EU"}}, "DE", "EU", true},
+
+		// Countries are exceptions to continents
+		{"permitted country in a denied continent", &GeoParams{PermitCountries: []string{"JP"}, DenyContinents: []string{"AS"}}, "JP", "AS", false},
+		{"other country in a denied continent", &GeoParams{PermitCountries: []string{"JP"}, DenyContinents: []string{"AS"}}, "CN", "AS", true},
+		{"denied country in a permitted continent", &GeoParams{DenyCountries: []string{"BY"}, PermitContinents: []string{"EU"}}, "BY", "EU", true},
+		{"other country in a permitted continent", &GeoParams{DenyCountries: []string{"BY"}, PermitContinents: []string{"EU"}}, "DE", "EU", false},
+		{"denied country, denied continent", &GeoParams{DenyCountries: []string{"RU"}, DenyContinents: []string{"AS"}}, "RU", "EU", true},
+
+		// Accounts on none of the lists are acted on only when a permit list is set
+		{"deny lists only", &GeoParams{DenyCountries: []string{"RU"}, DenyContinents: []string{"AS"}}, "US", "NA", false},
+		{"country permit list only", &GeoParams{PermitCountries: []string{"US"}, DenyContinents: []string{"AS"}}, "DE", "EU", true},
+		{"continent permit list only", &GeoParams{DenyCountries: []string{"RU"}, PermitContinents: []string{"EU"}}, "US", "NA", true},
+		{"both permit lists", &GeoParams{PermitCountries: []string{"US"}, PermitContinents: []string{"EU"}}, "BR", "SA", true},
+
+		// Missing GeoIP country or continent
+		{"no country, permit list", &GeoParams{PermitCountries: []string{"US"}}, "", "NA", true},
+		{"no country, deny list", &GeoParams{DenyCountries: []string{"RU"}}, "", "EU", false},
+		{"no country, permitted continent", &GeoParams{PermitCountries: []string{"US"}, PermitContinents: []string{"EU"}}, "", "EU", false},
+		{"no country, denied continent", &GeoParams{DenyContinents: []string{"EU"}}, "", "EU", true},
+		{"no continent, permitted country", &GeoParams{PermitCountries: []string{"US"}, PermitContinents: []string{"EU"}}, "US", "", false},
+		{"no continent, continent permit list", &GeoParams{PermitContinents: []string{"EU"}}, "DE", "", true},
+		{"no continent, continent deny list", &GeoParams{DenyContinents: []string{"AS"}}, "DE", "", false},
+		{"no country or continent, permit list", &GeoParams{PermitCountries: []string{"US"}}, "", "", true},
+		{"no country or continent, deny lists", &GeoParams{DenyCountries: []string{"RU"}, DenyContinents: []string{"AS"}}, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewGeoRule("geo", tt.params)
+			if err != nil {
+				t.Fatalf("NewGeoRule(): %v", err)
+			}
+			in := newTestInput("1", "192.0.2.1")
+			in.GeoIP.Country = tt.country
+			in.GeoIP.Continent = tt.continent
+
+			result, err := r.Evaluate(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (result != nil && result.Action == ActionAct) != tt.want {
+				t.Errorf("Evaluate() = %+v, want act %v", result, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewGeoRule("geo", &GeoParams{PermitCountries: []string{" "}}); err == nil {
+		t.Error("NewGeoRule() with empty lists succeeded, want an error")
+	}
+}
@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"io"
+	"sort"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/structs"
+)
+
+// Enforcement is a decision the worker enforced against an account,
+// read from the worker logs.
+type Enforcement struct {
+	// Time is when the decision was logged.
+	Time time.Time
+
+	// Decision is the decision record.
+	Decision structs.Decision
+
+	// Levels are the suspend levels still applied to the account by this
+	// and earlier enforcements, newest first. An escalation leaves the
+	// level it escalated from in place, and a lift clears the levels
+	// before it.
+	Levels []string
+}
+
+// EnforcementFilter selects the enforcements returned by ReadEnforcements.
+// Empty fields are not filtered on.
+type EnforcementFilter struct {
+	// AccountID selects enforcements against one account.
+	AccountID string
+
+	// Policy selects enforcements made by the named policy.
+	Policy string
+
+	// Since and Until select enforcements logged in a time range.
+	Since time.Time
+	Until time.Time
+}
+
+// match reports whether the enforcement is selected by the filter.
+func (f *EnforcementFilter) match(e *Enforcement) bool {
+	switch {
+	case f.AccountID != "" && e.Decision.Account.Id != f.AccountID:
+		return false
+	case f.Policy != "" && e.Decision.Policy != f.Policy:
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && e.Time.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// ReadEnforcements reads worker log lines (one JSON event per line) and
+// returns the last enforcement against each account, oldest first, if it
+// is selected by the filter. An account acted on again later, e.g. by
+// another policy, is only selected if its latest enforcement matches.
+// Decisions that were not enforced are skipped.
+// Follow-up escalations and lifts are enforcements too, logged without
+// the blocks created by the original action, so the last blocks logged
+// for the account are carried over to its last enforcement.
+func ReadEnforcements(r io.Reader, filter *EnforcementFilter) ([]Enforcement, error) {
+	if filter == nil {
+		filter = &EnforcementFilter{}
+	}
+
+	accounts := make(map[string][]Enforcement)
+	err := scanLog(r, func(entry *logLine) {
+		if entry.Decision == nil || !entry.Decision.Enforced {
+			return
+		}
+		e := Enforcement{Time: entry.Time, Decision: *entry.Decision}
+		accounts[e.Decision.Account.Id] = append(accounts[e.Decision.Account.Id], e)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	enforcements := []Enforcement{}
+	for _, history := range accounts {
+		sort.SliceStable(history, func(i, j int) bool {
+			return history[i].Time.Before(history[j].Time)
+		})
+		last := lastEnforcement(history)
+		if filter.match(&last) {
+			enforcements = append(enforcements, last)
+		}
+	}
+	sort.Slice(enforcements, func(i, j int) bool {
+		return enforcements[i].Time.Before(enforcements[j].Time)
+	})
+	return enforcements, nil
+}
+
+// lastEnforcement returns the last of an account's enforcements, oldest
+// first, with the blocks and levels of the earlier ones.
+func lastEnforcement(history []Enforcement) Enforcement {
+	last := history[len(history)-1]
+	seen := make(map[string]struct{})
+	for i := len(history) - 1; i >= 0; i-- {
+		d := &history[i].Decision
+		if last.Decision.IPBlock == nil {
+			last.Decision.IPBlock = d.IPBlock
+		}
+		if last.Decision.EmailBlock == nil {
+			last.Decision.EmailBlock = d.EmailBlock
+		}
+
+		if d.Action != string(ActionAct) || d.Level == "" {
+			continue
+		}
+		if d.Level == "none" {
+			// Lifted, or never restricted; nothing before it still applies
+			break
+		}
+		if _, ok := seen[d.Level]; !ok {
+			seen[d.Level] = struct{}{}
+			last.Levels = append(last.Levels, d.Level)
+		}
+	}
+	return last
+}
@@ -0,0 +1,132 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadEnforcementsFollowUps(t *testing.T) {
+	log := strings.Join([]string{
+		// Silenced with an IP block, then escalated to a suspension by a follow-up
+		`{"level":"info","Decision":{"account":{"id":"1"},"action":"act","level":"silence","enforced":true,"ip_block":{"id":"7","ip":"192.0.2.0/24","created":true}},"time":"2024-01-01T00:00:00Z"}`,
+		`{"level":"info","process":"followup::RunDue","outcome":"escalated","Decision":{"account":{"id":"1"},"action":"act","level":"suspend","enforced":true},"time":"2024-01-02T00:00:00Z"}`,
+		// Marked sensitive, then lifted by a follow-up
+		`{"level":"info","Decision":{"account":{"id":"2"},"action":"act","level":"sensitive","enforced":true},"time":"2024-01-01T00:00:00Z"}`,
+		`{"level":"info","process":"followup::RunDue","outcome":"lifted","Decision":{"account":{"id":"2"},"action":"act","level":"none","enforced":true},"time":"2024-01-08T00:00:00Z"}`,
+		// Logged out of order, and a decision that was not enforced
+		`{"level":"info","Decision":{"account":{"id":"3"},"action":"act","level":"suspend","enforced":true},"time":"2024-01-05T00:00:00Z"}`,
+		`{"level":"info","Decision":{"account":{"id":"3"},"action":"act","level":"silence","enforced":true},"time":"2024-01-03T00:00:00Z"}`,
+		`{"level":"info","Decision":{"account":{"id":"3"},"action":"act","level":"disable","enforced":false},"time":"2024-01-06T00:00:00Z"}`,
+	}, "\n")
+
+	enforcements, err := ReadEnforcements(strings.NewReader(log), &EnforcementFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]struct {
+		level   string
+		levels  []string
+		ipBlock string
+	}{
+		"1": {"suspend", []string{"suspend", "silence"}, "7"},
+		"2": {"none", nil, ""},
+		"3": {"suspend", []string{"suspend", "silence"}, ""},
+	}
+	if len(enforcements) != len(want) {
+		t.Fatalf("ReadEnforcements() returned %d enforcements, want %d", len(enforcements), len(want))
+	}
+	for _, e := range enforcements {
+		w, ok := want[e.Decision.Account.Id]
+		if !ok {
+			t.Errorf("unexpected enforcement for account %s", e.Decision.Account.Id)
+			continue
+		}
+		if e.Decision.Level != w.level {
+			t.Errorf("account %s: level = %q, want %q", e.Decision.Account.Id, e.Decision.Level, w.level)
+		}
+		if !reflect.DeepEqual(e.Levels, w.levels) {
+			t.Errorf("account %s: levels = %v, want %v", e.Decision.Account.Id, e.Levels, w.levels)
+		}
+		ipBlock := ""
+		if e.Decision.IPBlock != nil {
+			ipBlock = e.Decision.IPBlock.ID
+		}
+		if ipBlock != w.ipBlock {
+			t.Errorf("account %s: ip block = %q, want %q", e.Decision.Account.Id, ipBlock, w.ipBlock)
+		}
+	}
+}
+
+func TestReadEnforcementsFilter(t *testing.T) {
+	log := strings.Join([]string{
+		// Silenced by the signup policy, then suspended by the spam policy
+		`{"level":"info","Decision":{"account":{"id":"1"},"policy":"signup","action":"act","level":"silence","enforced":true},"time":"2024-01-01T00:00:00Z"}`,
+		`{"level":"info","Decision":{"account":{"id":"1"},"policy":"spam","action":"act","level":"suspend","enforced":true},"time":"2024-01-03T00:00:00Z"}`,
+		// Acted on by the signup policy alone
+		`{"level":"info","Decision":{"account":{"id":"2"},"policy":"signup","action":"act","level":"sensitive","enforced":true},"time":"2024-01-02T00:00:00Z"}`,
+	}, "\n")
+
+	tests := []struct {
+		name     string
+		filter   *EnforcementFilter
+		accounts []string
+	}{
+		{
+			name:     "no filter",
+			filter:   &EnforcementFilter{},
+			accounts: []string{"2", "1"},
+		},
+		{
+			name:     "earlier policy",
+			filter:   &EnforcementFilter{Policy: "signup"},
+			accounts: []string{"2"},
+		},
+		{
+			name:     "later policy",
+			filter:   &EnforcementFilter{Policy: "spam"},
+			accounts: []string{"1"},
+		},
+		{
+			name:     "account",
+			filter:   &EnforcementFilter{AccountID: "1"},
+			accounts: []string{"1"},
+		},
+		{
+			name:     "until before the later action",
+			filter:   &EnforcementFilter{Until: time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)},
+			accounts: []string{"2"},
+		},
+		{
+			name:     "since the later action",
+			filter:   &EnforcementFilter{Since: time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)},
+			accounts: []string{"1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enforcements, err := ReadEnforcements(strings.NewReader(log), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			accounts := []string{}
+			for _, e := range enforcements {
+				accounts = append(accounts, e.Decision.Account.Id)
+			}
+			if !reflect.DeepEqual(accounts, tt.accounts) {
+				t.Errorf("accounts = %v, want %v", accounts, tt.accounts)
+			}
+		})
+	}
+
+	enforcements, err := ReadEnforcements(strings.NewReader(log), &EnforcementFilter{Policy: "spam"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if levels := enforcements[0].Levels; !reflect.DeepEqual(levels, []string{"suspend", "silence"}) {
+		t.Errorf("levels = %v, want both policies' levels", levels)
+	}
+}
@@ -0,0 +1,122 @@
+package policy
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// urlPattern matches text that looks like a link: anything with a scheme or
+// "www." prefix, or a domain name followed by a path.
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}/\S*`)
+
+// InviteParams configures an InviteRule, which checks the invite_request
+// text ("why do you want to join?") sent with sign ups on instances that
+// require approval.
+type InviteParams struct {
+	// Keywords lists words or phrases to act on. Matching is case-insensitive.
+	Keywords []string `json:"keywords"`
+
+	// Patterns lists regular expressions matched against the text.
+	// Use the (?i) flag for case-insensitive patterns.
+	Patterns []string `json:"patterns"`
+
+	// MinLength acts on text shorter than this many characters,
+	// including an empty invite request.
+	MinLength int `json:"min_length"`
+
+	// ContainsURL acts on text containing a link.
+	ContainsURL bool `json:"contains_url"`
+}
+
+// InviteRule acts on accounts by the text of their invite request.
+// Accounts that are already approved are skipped, so the rule
+// only applies on instances that require approval.
+type InviteRule struct {
+	name        string
+	keywords    []string
+	patterns    []*regexp.Regexp
+	minLength   int
+	containsURL bool
+}
+
+// NewInviteRule creates a new InviteRule.
+func NewInviteRule(name string, params *InviteParams) (*InviteRule, error) {
+	r := &InviteRule{
+		name:        name,
+		minLength:   params.MinLength,
+		containsURL: params.ContainsURL,
+	}
+
+	for _, keyword := range params.Keywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			r.keywords = append(r.keywords, keyword)
+		}
+	}
+	for _, pattern := range params.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, &InvalidRule{Name: name, Msg: "invalid pattern '" + pattern + "'", Err: err}
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	if len(r.keywords) == 0 && len(r.patterns) == 0 && r.minLength <= 0 && !r.containsURL {
+		return nil, &InvalidRule{Name: name, Msg: "no keywords, patterns, min_length or contains_url"}
+	}
+	return r, nil
+}
+
+// Name returns the name of the rule.
+func (r *InviteRule) Name() string {
+	return r.name
+}
+
+// Evaluate acts on pending accounts whose invite request is too short,
+// contains a link, a keyword, or matches a pattern.
+func (r *InviteRule) Evaluate(in *Input) (*Result, error) {
+	if in.Event.Object.Approved {
+		return nil, nil
+	}
+	text := strings.TrimSpace(in.Event.Object.InviteRequest)
+
+	if length := utf8.RuneCountInString(text); length < r.minLength {
+		return &Result{
+			Action: ActionAct,
+			Reason: "invite request is " + strconv.Itoa(length) + " characters, shorter than " + strconv.Itoa(r.minLength),
+		}, nil
+	}
+
+	if r.containsURL {
+		if match := urlPattern.FindString(text); match != "" {
+			return &Result{
+				Action: ActionAct,
+				Reason: "invite request contains a link",
+				Match:  match,
+			}, nil
+		}
+	}
+
+	lower := strings.ToLower(text)
+	for _, keyword := range r.keywords {
+		if strings.Contains(lower, keyword) {
+			return &Result{
+				Action: ActionAct,
+				Reason: "invite request contains the keyword '" + keyword + "'",
+				Match:  keyword,
+			}, nil
+		}
+	}
+
+	for _, re := range r.patterns {
+		if re.MatchString(text) {
+			return &Result{
+				Action: ActionAct,
+				Reason: "invite request matches the pattern '" + re.String() + "'",
+				Match:  re.String(),
+			}, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"net"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/mastoclient"
+)
+
+// IPBlockParams configures a Mastodon IP block for the account's IP
+// address, created when the rule acts on or rejects the account.
+type IPBlockParams struct {
+	// Severity is sign_up_requires_approval, sign_up_block or no_access.
+	Severity string `json:"severity"`
+
+	// IPv4Prefix and IPv6Prefix set the size of the blocked network, e.g.
+	// 24 and 64. They default to 32 and 128, the IP address alone.
+	IPv4Prefix int `json:"ipv4_prefix,omitempty"`
+	IPv6Prefix int `json:"ipv6_prefix,omitempty"`
+
+	// ExpiresIn is how long the block lasts, e.g. "720h".
+	// Blocks never expire by default.
+	ExpiresIn string `json:"expires_in,omitempty"`
+
+	// expiresIn is the parsed ExpiresIn.
+	expiresIn time.Duration
+}
+
+// IPBlock is the Mastodon IP block to create for a decision.
+type IPBlock struct {
+	// Network is the network to block.
+	Network *net.IPNet
+
+	// Severity is the IP block severity.
+	Severity string
+
+	// ExpiresIn is how long the block lasts. Zero never expires.
+	ExpiresIn time.Duration
+}
+
+// validate checks the IP block parameters and fills in the defaults.
+func (p *IPBlockParams) validate(name string) error {
+	if !mastoclient.ValidIPBlockSeverity(p.Severity) {
+		return &InvalidRule{Name: name, Msg: "invalid ip_block severity '" + p.Severity + "'"}
+	}
+
+	if p.IPv4Prefix == 0 {
+		p.IPv4Prefix = 32
+	}
+	if p.IPv6Prefix == 0 {
+		p.IPv6Prefix = 128
+	}
+	if p.IPv4Prefix < 1 || p.IPv4Prefix > 32 || p.IPv6Prefix < 1 || p.IPv6Prefix > 128 {
+		return &InvalidRule{Name: name, Msg: "invalid ip_block prefix length"}
+	}
+
+	if p.ExpiresIn != "" {
+		expiresIn, err := time.ParseDuration(p.ExpiresIn)
+		if err != nil {
+			return &InvalidRule{Name: name, Msg: "invalid ip_block expires_in", Err: err}
+		}
+		if expiresIn <= 0 {
+			return &InvalidRule{Name: name, Msg: "ip_block expires_in must be greater than zero"}
+		}
+		p.expiresIn = expiresIn
+	}
+	return nil
+}
+
+// block returns the IP block for the network containing ip.
+func (p *IPBlockParams) block(ip net.IP) *IPBlock {
+	if ip == nil {
+		return nil
+	}
+
+	mask := net.CIDRMask(p.IPv6Prefix, 128)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, mask = ip4, net.CIDRMask(p.IPv4Prefix, 32)
+	}
+	return &IPBlock{
+		Network:   &net.IPNet{IP: ip.Mask(mask), Mask: mask},
+		Severity:  p.Severity,
+		ExpiresIn: p.expiresIn,
+	}
+}
+
+// String returns the IP block as "<network> (<severity>, <expiry>)".
+func (b *IPBlock) String() string {
+	expiry := "never expires"
+	if b.ExpiresIn > 0 {
+		expiry = "expires in " + b.ExpiresIn.String()
+	}
+	return b.Network.String() + " (" + b.Severity + ", " + expiry + ")"
+}
@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"net"
+)
+
+// IPClass classifies IP addresses that cannot be judged by where they are.
+type IPClass string
+
+const (
+	// ClassPublic is a routable address with a GeoIP country.
+	ClassPublic IPClass = ""
+
+	// ClassPrivate is an RFC 1918 or IPv6 unique local address.
+	ClassPrivate IPClass = "private"
+
+	// ClassCGNAT is a carrier-grade NAT shared address (100.64.0.0/10).
+	ClassCGNAT IPClass = "cgnat"
+
+	// ClassLoopback is a loopback address.
+	ClassLoopback IPClass = "loopback"
+
+	// ClassLinkLocal is a link-local address.
+	ClassLinkLocal IPClass = "link_local"
+
+	// ClassDocumentation is an address reserved for documentation.
+	ClassDocumentation IPClass = "documentation"
+
+	// ClassReserved is an unspecified, multicast, broadcast or otherwise reserved address.
+	ClassReserved IPClass = "reserved"
+
+	// ClassUnknownCountry is an address GeoIP has no country for.
+	ClassUnknownCountry IPClass = "unknown_country"
+)
+
+// IPClassRule is the rule name recorded on decisions reached by the IP class.
+const IPClassRule = "ip-class"
+
+// ActionEvaluate is used in a policy's ip_classes to evaluate
+// addresses in the class against the rules as usual.
+const ActionEvaluate Action = "evaluate"
+
+// DefaultIPClassAction is applied to every class not configured in the policy.
+const DefaultIPClassAction = ActionHold
+
+// ipClassPrefixes maps address ranges to their class.
+var ipClassPrefixes = []struct {
+	prefix string
+	class  IPClass
+}{
+	{"127.0.0.0/8", ClassLoopback},
+	{"::1/128", ClassLoopback},
+	{"10.0.0.0/8", ClassPrivate},
+	{"172.16.0.0/12", ClassPrivate},
+	{"192.168.0.0/16", ClassPrivate},
+	{"fc00::/7", ClassPrivate},
+	{"100.64.0.0/10", ClassCGNAT},
+	{"169.254.0.0/16", ClassLinkLocal},
+	{"fe80::/10", ClassLinkLocal},
+	{"192.0.2.0/24", ClassDocumentation},
+	{"198.51.100.0/24", ClassDocumentation},
+	{"203.0.113.0/24", ClassDocumentation},
+	{"2001:db8::/32", ClassDocumentation},
+	{"0.0.0.0/8", ClassReserved},
+	{"224.0.0.0/4", ClassReserved},
+	{"240.0.0.0/4", ClassReserved},
+	{"::/128", ClassReserved},
+	{"ff00::/8", ClassReserved},
+}
+
+// ipClassNets holds the parsed ipClassPrefixes, in the same order.
+var ipClassNets = func() []*net.IPNet {
+	prefixes := []*net.IPNet{}
+	for _, p := range ipClassPrefixes {
+		_, prefix, err := net.ParseCIDR(p.prefix)
+		if err != nil {
+			panic(err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}()
+
+// ClassifyIP returns the class of an IP address by its range alone.
+// Public addresses return ClassPublic; ClassUnknownCountry is
+// only known after the GeoIP lookup.
+func ClassifyIP(ip net.IP) IPClass {
+	for i, prefix := range ipClassNets {
+		if prefix.Contains(ip) {
+			return ipClassPrefixes[i].class
+		}
+	}
+	return ClassPublic
+}
+
+// ipClassActions returns the action for every IP class, applying the
+// policy's ip_classes over DefaultIPClassAction.
+func ipClassActions(configured map[IPClass]Action) (map[IPClass]Action, error) {
+	actions := map[IPClass]Action{
+		ClassPrivate:        DefaultIPClassAction,
+		ClassCGNAT:          DefaultIPClassAction,
+		ClassLoopback:       DefaultIPClassAction,
+		ClassLinkLocal:      DefaultIPClassAction,
+		ClassDocumentation:  DefaultIPClassAction,
+		ClassReserved:       DefaultIPClassAction,
+		ClassUnknownCountry: DefaultIPClassAction,
+	}
+	for class, action := range configured {
+		if _, ok := actions[class]; !ok {
+			return nil, &InvalidPolicy{Msg: "unknown ip class '" + string(class) + "'"}
+		}
+		switch action {
+		case ActionAct, ActionHold, ActionAllow, ActionReject, ActionEvaluate:
+		default:
+			return nil, &InvalidPolicy{Msg: "invalid action '" + string(action) + "' for ip class '" + string(class) + "'"}
+		}
+		actions[class] = action
+	}
+	return actions, nil
+}
@@ -0,0 +1,147 @@
+package policy
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/structs"
+)
+
+// IPMode chooses how the IP addresses in an account's history are evaluated.
+type IPMode string
+
+const (
+	// IPModeLatest evaluates the account IP only, which is the address
+	// the account last used. The rest of the history is looked up and
+	// recorded, but not evaluated.
+	IPModeLatest IPMode = "latest"
+
+	// IPModeAnyDeny evaluates every IP address, and acts if the
+	// decision for any of them is to act (or reject).
+	IPModeAnyDeny IPMode = "any_deny"
+
+	// IPModeAllPermit evaluates every IP address, and allows the account
+	// only if every one of them is allowed. Otherwise the most severe
+	// decision (act or reject, then hold) applies.
+	IPModeAllPermit IPMode = "all_permit"
+)
+
+// IPDecision is the decision for one IP address in an account's history.
+type IPDecision struct {
+	// UsedAt is when the account last used the address, if known.
+	UsedAt time.Time
+
+	// Decision is the decision for the account from the address. In
+	// IPModeLatest, history addresses are only looked up, and their
+	// Action is empty.
+	Decision *Decision
+}
+
+// evaluateHistory applies the IP mode to the decision for the account IP,
+// evaluating the rest of the IP history as needed. Errors for addresses in
+// the history are recorded, but do not change the decision.
+func (e *Engine) evaluateHistory(event *structs.AccoutCreatedEvent, primary *Decision) *Decision {
+	history := historyIPs(event)
+	if len(history) == 0 {
+		return primary
+	}
+
+	ips := []IPDecision{{Decision: primary}}
+	for _, ip := range history {
+		if ip.Ip == event.Object.Ip {
+			ips[0].UsedAt = ip.UsedAt.Time
+			continue
+		}
+
+		var decision *Decision
+		if e.ipMode == IPModeAnyDeny || e.ipMode == IPModeAllPermit {
+			decision = e.evaluateIP(event, ip.Ip)
+		} else {
+			decision = e.lookupIP(event, ip.Ip)
+		}
+		ips = append(ips, IPDecision{UsedAt: ip.UsedAt.Time, Decision: decision})
+	}
+
+	deciding := 0
+	if primary.Action != ActionError {
+		switch e.ipMode {
+		case IPModeAnyDeny:
+			for i, ip := range ips {
+				if severity(ip.Decision.Action) == severity(ActionAct) {
+					deciding = i
+					break
+				}
+			}
+		case IPModeAllPermit:
+			for i, ip := range ips {
+				if severity(ip.Decision.Action) > severity(ips[deciding].Decision.Action) {
+					deciding = i
+				}
+			}
+		}
+	}
+
+	result := primary
+	if deciding > 0 {
+		// The outcome comes from a history address, but the record keeps
+		// the account IP's enrichment and rules, with the deciding address
+		// and its rules alongside.
+		decided := *ips[deciding].Decision
+		decided.Rules = primary.Rules
+		decided.Input = primary.Input
+		decided.DecidingIP = &ips[deciding]
+		result = &decided
+	}
+
+	result.IPs = ips
+	return result
+}
+
+// lookupIP classifies and looks up an IP address without evaluating any rules.
+func (e *Engine) lookupIP(event *structs.AccoutCreatedEvent, ip string) *Decision {
+	in := &Input{Event: event, IP: net.ParseIP(ip)}
+	if in.IP == nil {
+		return &Decision{Action: ActionError, Err: &InvalidIP{IP: ip}, Input: in}
+	}
+
+	in.IPClass = ClassifyIP(in.IP)
+	ipData, err := e.geoIP.Lookup(in.IP)
+	if err != nil {
+		return &Decision{Action: ActionError, Err: &LookupFailed{IP: ip, Err: err}, Input: in}
+	}
+	in.GeoIP = ipData
+	if in.IPClass == ClassPublic && ipData.Country == "" {
+		in.IPClass = ClassUnknownCountry
+	}
+	return &Decision{Input: in}
+}
+
+// historyIPs returns the account's IP history, most recently used first.
+func historyIPs(event *structs.AccoutCreatedEvent) []structs.EventIP {
+	seen := make(map[string]struct{})
+	ips := []structs.EventIP{}
+	for _, ip := range event.Object.Ips {
+		if _, ok := seen[ip.Ip]; ok || ip.Ip == "" {
+			continue
+		}
+		seen[ip.Ip] = struct{}{}
+		ips = append(ips, ip)
+	}
+
+	sort.SliceStable(ips, func(i, j int) bool {
+		return ips[i].UsedAt.After(ips[j].UsedAt.Time)
+	})
+	return ips
+}
+
+// severity orders actions for IPModeAllPermit.
+func severity(action Action) int {
+	switch action {
+	case ActionAct, ActionReject:
+		return 2
+	case ActionHold:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,107 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/structs"
+)
+
+func TestEvaluateHistory(t *testing.T) {
+	// Accounts from Russia are acted on and accounts from Japan held,
+	// see newTestEngine for the test addresses.
+	rules := []RuleDefinition{denyRule("ru", "RU", "", false), denyRule("jp", "JP", ActionHold, false)}
+
+	// history lists the addresses the account used before the account IP, most recent first
+	tests := []struct {
+		name      string
+		mode      IPMode
+		ip        string
+		history   []string
+		action    Action
+		deciding  string
+		countries []string
+	}{
+		// Latest only looks the history up
+		{"latest, denied history", IPModeLatest, "1.0.0.1", []string{"2.0.0.1"}, ActionAllow, "", []string{"US", "RU"}},
+		{"latest, denied account ip", IPModeLatest, "2.0.0.1", []string{"1.0.0.1"}, ActionAct, "", []string{"RU", "US"}},
+		{"default mode is latest", "", "1.0.0.1", []string{"2.0.0.1"}, ActionAllow, "", []string{"US", "RU"}},
+		{"no history", IPModeAnyDeny, "1.0.0.1", nil, ActionAllow, "", nil},
+
+		// Any deny acts if any address acts
+		{"any deny, denied history", IPModeAnyDeny, "1.0.0.1", []string{"3.0.0.1", "2.0.0.1"}, ActionAct, "2.0.0.1", []string{"US", "JP", "RU"}},
+		{"any deny, denied account ip", IPModeAnyDeny, "2.0.0.1", []string{"2.0.0.2"}, ActionAct, "", []string{"RU", "RU"}},
+		{"any deny, held history", IPModeAnyDeny, "1.0.0.1", []string{"3.0.0.1"}, ActionAllow, "", []string{"US", "JP"}},
+		{"any deny, allowed history", IPModeAnyDeny, "1.0.0.1", []string{"1.0.0.2"}, ActionAllow, "", []string{"US", "US"}},
+
+		// All permit takes the most severe decision
+		{"all permit, held history", IPModeAllPermit, "1.0.0.1", []string{"3.0.0.1"}, ActionHold, "3.0.0.1", []string{"US", "JP"}},
+		{"all permit, denied and held history", IPModeAllPermit, "1.0.0.1", []string{"3.0.0.1", "2.0.0.1"}, ActionAct, "2.0.0.1", []string{"US", "JP", "RU"}},
+		{"all permit, first of equal severity", IPModeAllPermit, "1.0.0.1", []string{"2.0.0.1", "2.0.0.2"}, ActionAct, "2.0.0.1", []string{"US", "RU", "RU"}},
+		{"all permit, held account ip", IPModeAllPermit, "3.0.0.1", []string{"1.0.0.1"}, ActionHold, "", []string{"JP", "US"}},
+		{"all permit, allowed history", IPModeAllPermit, "1.0.0.1", []string{"1.0.0.2"}, ActionAllow, "", []string{"US", "US"}},
+
+		// Errors in the history are recorded, but don't change the decision
+		{"any deny, invalid history", IPModeAnyDeny, "1.0.0.1", []string{"not an ip"}, ActionAllow, "", []string{"US", ""}},
+		{"latest, invalid history", IPModeLatest, "1.0.0.1", []string{"not an ip"}, ActionAllow, "", []string{"US", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, &Policy{IPMode: tt.mode, Rules: rules})
+
+			now := time.Now()
+			event := newTestInput("1", tt.ip).Event
+			event.Object.Ips = []structs.EventIP{{Ip: tt.ip, UsedAt: structs.Time{Time: now}}}
+			for i, ip := range tt.history {
+				event.Object.Ips = append(event.Object.Ips, structs.EventIP{Ip: ip, UsedAt: structs.Time{Time: now.Add(-time.Duration(i+1) * time.Hour)}})
+			}
+			if tt.history == nil {
+				event.Object.Ips = nil
+			}
+			d := e.Evaluate(event)
+
+			if d.Action != tt.action {
+				t.Errorf("Evaluate() = %s, want %s", d.Action, tt.action)
+			}
+			if got := d.Input.IP.String(); got != tt.ip {
+				t.Errorf("Input.IP = %s, want the account IP %s", got, tt.ip)
+			}
+
+			// The record shows the deciding address and each address's country
+			record := d.Record()
+			deciding := ""
+			if record.DecidingIP != nil {
+				deciding = record.DecidingIP.IP
+				if len(record.DecidingIP.Rules) == 0 {
+					t.Error("DecidingIP.Rules is empty, want the deciding address's rules")
+				}
+			}
+			if deciding != tt.deciding {
+				t.Errorf("DecidingIP = %q, want %q", deciding, tt.deciding)
+			}
+
+			var countries []string
+			for i, ip := range record.IPs {
+				countries = append(countries, ip.Country)
+				if ip.UsedAt == nil {
+					t.Errorf("IPs[%d].UsedAt is not set", i)
+				}
+			}
+			if !reflect.DeepEqual(countries, tt.countries) {
+				t.Errorf("IP countries = %v, want %v", countries, tt.countries)
+			}
+
+			// In latest mode, the history is looked up without evaluating any rules
+			if tt.mode != IPModeLatest && tt.mode != "" {
+				return
+			}
+			for i := 1; i < len(d.IPs); i++ {
+				if action := d.IPs[i].Decision.Action; action != "" && action != ActionError {
+					t.Errorf("IPs[%d].Action = %s, want the history looked up only", i, action)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package policy
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/mastoclient"
+)
+
+// Policy is the on-disk policy document. It names the policy and lists
+// the rules the engine evaluates, in order.
+type Policy struct {
+	// Name is a friendly name for the policy.
+	Name string `json:"name"`
+
+	// Version is recorded alongside every decision made with the policy.
+	Version string `json:"version"`
+
+	// Lists are named lists for use in expressions, see in_list() and domain_in_list().
+	Lists map[string]*ListDefinition `json:"lists"`
+
+	// Rules are evaluated in order. The first rule to reach a decision wins.
+	Rules []RuleDefinition `json:"rules"`
+
+	// IPClasses sets the action for addresses that can't be judged by where
+	// they are: private, cgnat, loopback, link_local, documentation, reserved
+	// and unknown_country. Classes not listed default to hold. Use "evaluate"
+	// to run addresses in the class through the rules as usual.
+	IPClasses map[IPClass]Action `json:"ip_classes"`
+
+	// IPMode chooses how the account's IP history is evaluated:
+	// latest (the default), any_deny or all_permit. See IPMode.
+	IPMode IPMode `json:"ip_mode"`
+
+	// Thresholds map the risk score from weighted rules to a suspend level.
+	// They apply when no unweighted rule reaches a decision.
+	Thresholds []Threshold `json:"thresholds"`
+
+	// Timezone is the IANA timezone for rule schedules, e.g. "America/New_York".
+	// Defaults to UTC.
+	Timezone string `json:"timezone"`
+
+	// Approval is for instances that require approval. Pending accounts the
+	// policy allows are approved, and pending accounts it acts on are
+	// rejected. Held accounts are left for a moderator either way.
+	Approval bool `json:"approval"`
+
+	// DryRun puts the whole policy in dry run mode, see WithDryRun.
+	DryRun bool `json:"dry_run"`
+
+	// built caches the rules returned by Build.
+	built []Rule
+}
+
+// ListDefinition describes a named list of items. Items
+// can be given inline, in a file (one per line), or both.
+type ListDefinition struct {
+	Items []string `json:"items"`
+	File  string   `json:"file"`
+}
+
+// RuleDefinition describes a single rule in a policy document.
+// Exactly one of the rule type fields must be set.
+type RuleDefinition struct {
+	// Name identifies the rule in logs and decisions.
+	Name string `json:"name"`
+
+	// Weight makes the rule a risk signal. Instead of deciding, a match adds
+	// the weight (which may be negative) to the account's risk score, and the
+	// policy thresholds decide. Weighted rules cannot set Action.
+	Weight int `json:"weight,omitempty"`
+
+	// Action replaces ActionAct when the rule acts on an account,
+	// e.g. "hold" to leave matches for a moderator to review, "allow"
+	// to let matches through without checking the remaining rules, or
+	// "approve" or "reject" for pending accounts. Defaults to "act".
+	Action Action `json:"action,omitempty"`
+
+	// Level is the suspend level applied when the rule acts on an account:
+	// sensitive, disable, silence or suspend. Defaults to MASTODON_SUSPEND_LEVEL.
+	Level string `json:"level,omitempty"`
+
+	// Text is the message sent with the suspension, as a text/template
+	// executed with TextData. Defaults to MASTODON_SUSPEND_TEXT.
+	Text string `json:"text,omitempty"`
+
+	// NotBefore and NotAfter limit when the rule applies, as RFC 3339 times,
+	// e.g. to expire a lockdown rule. Times without a UTC offset are in the
+	// policy timezone.
+	NotBefore string `json:"not_before,omitempty"`
+	NotAfter  string `json:"not_after,omitempty"`
+
+	// Schedule limits the rule to weekly windows, e.g. overnight when no
+	// moderators are around. The rule applies in any of the windows.
+	Schedule []ScheduleWindow `json:"schedule,omitempty"`
+
+	// IPBlock also blocks the account's IP address, or its network, in
+	// Mastodon when the rule acts on or rejects the account.
+	IPBlock *IPBlockParams `json:"ip_block,omitempty"`
+
+	// EmailBlock also blocks the account's email address, or its email
+	// domain once it has produced enough suspensions, in Mastodon when the
+	// rule acts on or rejects the account.
+	EmailBlock *EmailBlockParams `json:"email_block,omitempty"`
+
+	// FollowUp schedules an escalation, or the lifting of the rule's
+	// level, for accounts the rule acts on.
+	FollowUp *FollowUpParams `json:"follow_up,omitempty"`
+
+	// DryRun records the decision the rule would have made instead of
+	// enforcing it, and evaluation carries on with the remaining rules.
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Geo configures a country and continent rule.
+	Geo *GeoParams `json:"geo,omitempty"`
+
+	// CIDR configures a network prefix rule.
+	CIDR *CIDRParams `json:"cidr,omitempty"`
+
+	// ASN configures an autonomous system rule.
+	ASN *ASNParams `json:"asn,omitempty"`
+
+	// Email configures an email domain rule.
+	Email *EmailParams `json:"email,omitempty"`
+
+	// Username configures a username rule.
+	Username *UsernameParams `json:"username,omitempty"`
+
+	// Invite configures an invite request rule.
+	Invite *InviteParams `json:"invite,omitempty"`
+
+	// Expr configures an expression rule.
+	Expr *ExprParams `json:"expr,omitempty"`
+
+	// Velocity configures a signup velocity rule.
+	Velocity *VelocityParams `json:"velocity,omitempty"`
+}
+
+// Load reads and parses a JSON policy document from the given file.
+func Load(policyFile string) (*Policy, error) {
+	fqpn, err := filepath.Abs(policyFile)
+	if err != nil {
+		return nil, &InvalidPolicy{Msg: "filepath.Abs returned an error parsing policy file path '" + policyFile + "'", Err: err}
+	}
+
+	data, err := os.ReadFile(fqpn)
+	if err != nil {
+		return nil, &InvalidPolicy{Msg: "unable to read policy file '" + fqpn + "'", Err: err}
+	}
+
+	return Parse(data)
+}
+
+// Parse parses and validates a JSON policy document.
+func Parse(data []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, &InvalidPolicy{Msg: "unable to parse policy", Err: err}
+	}
+	if _, err := p.Build(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Build validates the policy document and returns the rules it defines.
+// Lists are loaded and expressions are compiled here, so errors in the
+// policy are reported when it is loaded rather than when it is evaluated.
+func (p *Policy) Build() ([]Rule, error) {
+	if p.built != nil {
+		return p.built, nil
+	}
+
+	rules := []Rule{}
+	names := make(map[string]struct{})
+
+	if err := p.validateThresholds(); err != nil {
+		return nil, err
+	}
+	if _, err := ipClassActions(p.IPClasses); err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return nil, &InvalidPolicy{Msg: "invalid timezone '" + p.Timezone + "'", Err: err}
+	}
+
+	switch p.IPMode {
+	case "", IPModeLatest, IPModeAnyDeny, IPModeAllPermit:
+	default:
+		return nil, &InvalidPolicy{Msg: "invalid ip_mode '" + string(p.IPMode) + "'"}
+	}
+
+	lists := make(map[string][]string)
+	for name, list := range p.Lists {
+		items := list.Items
+		if list.File != "" {
+			fileItems, err := readListFile(list.File)
+			if err != nil {
+				return nil, &InvalidPolicy{Msg: "unable to load list '" + name + "'", Err: err}
+			}
+			items = append(items, fileItems...)
+		}
+		lists[name] = items
+	}
+
+	for i := range p.Rules {
+		def := &p.Rules[i]
+		if def.Name == "" {
+			return nil, &InvalidRule{Msg: "rule name is required"}
+		}
+		if _, ok := names[def.Name]; ok {
+			return nil, &InvalidRule{Name: def.Name, Msg: "duplicate rule name"}
+		}
+		names[def.Name] = struct{}{}
+
+		rule, err := def.build(lists, loc)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	p.built = rules
+	return rules, nil
+}
+
+// build creates the rule described by the definition.
+func (def *RuleDefinition) build(lists map[string][]string, loc *time.Location) (Rule, error) {
+	var rule Rule
+	var err error
+	count := 0
+
+	if def.Geo != nil {
+		count++
+		rule, err = NewGeoRule(def.Name, def.Geo)
+	}
+	if def.CIDR != nil {
+		count++
+		rule, err = NewCIDRRule(def.Name, def.CIDR)
+	}
+	if def.ASN != nil {
+		count++
+		rule, err = NewASNRule(def.Name, def.ASN)
+	}
+	if def.Email != nil {
+		count++
+		rule, err = NewEmailRule(def.Name, def.Email)
+	}
+	if def.Username != nil {
+		count++
+		rule, err = NewUsernameRule(def.Name, def.Username)
+	}
+	if def.Invite != nil {
+		count++
+		rule, err = NewInviteRule(def.Name, def.Invite)
+	}
+	if def.Expr != nil {
+		count++
+		rule, err = NewExprRule(def.Name, def.Expr, lists)
+	}
+	if def.Velocity != nil {
+		count++
+		rule, err = NewVelocityRule(def.Name, def.Velocity)
+	}
+
+	switch {
+	case count == 0:
+		return nil, &InvalidRule{Name: def.Name, Msg: "no rule type set"}
+	case count > 1:
+		return nil, &InvalidRule{Name: def.Name, Msg: "more than one rule type set"}
+	case err != nil:
+		return nil, err
+	}
+
+	switch def.Action {
+	case "", ActionAct, ActionHold, ActionAllow, ActionApprove, ActionReject:
+	default:
+		return nil, &InvalidRule{Name: def.Name, Msg: "invalid action '" + string(def.Action) + "'"}
+	}
+	if def.Weight != 0 && def.Action != "" {
+		return nil, &InvalidRule{Name: def.Name, Msg: "action cannot be set on a weighted rule"}
+	}
+	if def.Weight != 0 && (def.Level != "" || def.Text != "") {
+		return nil, &InvalidRule{Name: def.Name, Msg: "level and text cannot be set on a weighted rule, set them on the thresholds"}
+	}
+	if def.Level != "" && !mastoclient.ValidSuspendLevel(def.Level) {
+		return nil, &InvalidRule{Name: def.Name, Msg: "invalid level '" + def.Level + "'"}
+	}
+	if def.IPBlock != nil {
+		if def.Weight != 0 {
+			return nil, &InvalidRule{Name: def.Name, Msg: "ip_block cannot be set on a weighted rule"}
+		}
+		if err := def.IPBlock.validate(def.Name); err != nil {
+			return nil, err
+		}
+	}
+	if def.EmailBlock != nil {
+		if def.Weight != 0 {
+			return nil, &InvalidRule{Name: def.Name, Msg: "email_block cannot be set on a weighted rule"}
+		}
+		if err := def.EmailBlock.validate(def.Name); err != nil {
+			return nil, err
+		}
+	}
+	if def.FollowUp != nil {
+		if def.Weight != 0 {
+			return nil, &InvalidRule{Name: def.Name, Msg: "follow_up cannot be set on a weighted rule"}
+		}
+		if err := def.FollowUp.validate(def.Name, def.Action, def.Level); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &definedRule{Rule: rule, def: def}
+	if r.schedule, err = newSchedule(def, loc); err != nil {
+		return nil, err
+	}
+	if def.Text != "" {
+		if r.text, err = parseText(def.Name, def.Text); err != nil {
+			return nil, &InvalidRule{Name: def.Name, Msg: "invalid text template", Err: err}
+		}
+	}
+	return r, nil
+}
+
+// definedRule wraps a rule built from a RuleDefinition and applies
+// the settings shared by every rule type.
+type definedRule struct {
+	Rule
+	def      *RuleDefinition
+	text     *template.Template
+	schedule *schedule
+}
+
+// PreLookup reports whether the wrapped rule runs before the GeoIP lookup.
+func (r *definedRule) PreLookup() bool {
+	pre, ok := r.Rule.(PreLookupRule)
+	return ok && pre.PreLookup()
+}
+
+// inactive returns an empty string if the rule applies at t,
+// or the reason it does not.
+func (r *definedRule) inactive(t time.Time) string {
+	if r.schedule == nil {
+		return ""
+	}
+	return r.schedule.active(t)
+}
+
+// Evaluate runs the wrapped rule and applies the rule definition's action.
+func (r *definedRule) Evaluate(in *Input) (*Result, error) {
+	res, err := r.Rule.Evaluate(in)
+	if err != nil || res == nil {
+		return res, err
+	}
+	if res.Action == ActionAct && r.def.Action != "" {
+		res.Action = r.def.Action
+	}
+	return res, nil
+}
+
+// unwrapRule returns the rule wrapped by a definedRule, or the rule itself.
+func unwrapRule(rule Rule) Rule {
+	if r, ok := rule.(*definedRule); ok {
+		return r.Rule
+	}
+	return rule
+}
+
+// readListFile reads a list file with one item per line.
+// Blank lines and lines starting with '#' are ignored.
+func readListFile(listFile string) ([]string, error) {
+	fqpn, err := filepath.Abs(listFile)
+	if err != nil {
+		return nil, &InvalidPolicy{Msg: "filepath.Abs returned an error parsing list file path '" + listFile + "'", Err: err}
+	}
+
+	f, err := os.Open(fqpn)
+	if err != nil {
+		return nil, &InvalidPolicy{Msg: "unable to open list file '" + fqpn + "'", Err: err}
+	}
+	defer f.Close()
+
+	items := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		items = append(items, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &InvalidPolicy{Msg: "unable to read list file '" + fqpn + "'", Err: err}
+	}
+	return items, nil
+}
@@ -0,0 +1,107 @@
+package policy
+
+import (
+	"net"
+)
+
+// prefixTree is a binary trie of IP prefixes supporting longest prefix match.
+// IPv4 prefixes are stored as IPv4-mapped IPv6 prefixes so both address
+// families share a single tree.
+type prefixTree struct {
+	root *prefixNode
+	size int
+}
+
+// prefixNode is a single bit position in the trie.
+type prefixNode struct {
+	children [2]*prefixNode
+	entry    *prefixEntry
+}
+
+// prefixEntry is the value stored against a prefix.
+type prefixEntry struct {
+	prefix *net.IPNet
+	action Action
+}
+
+// newPrefixTree creates an empty prefix tree.
+func newPrefixTree() *prefixTree {
+	return &prefixTree{root: &prefixNode{}}
+}
+
+// insert stores the action against the prefix. If the prefix is
+// already present, ActionAct wins over any other action.
+func (t *prefixTree) insert(prefix *net.IPNet, action Action) {
+	ip, bits := prefixBits(prefix)
+
+	node := t.root
+	for i := 0; i < bits; i++ {
+		bit := bitAt(ip, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &prefixNode{}
+		}
+		node = node.children[bit]
+	}
+
+	if node.entry == nil {
+		t.size++
+		node.entry = &prefixEntry{prefix: prefix, action: action}
+		return
+	}
+	if action == ActionAct {
+		node.entry = &prefixEntry{prefix: prefix, action: action}
+	}
+}
+
+// lookup returns the entry for the longest prefix containing ip, or nil.
+func (t *prefixTree) lookup(ip net.IP) *prefixEntry {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return nil
+	}
+
+	var match *prefixEntry
+	node := t.root
+	for i := 0; node != nil; i++ {
+		if node.entry != nil {
+			match = node.entry
+		}
+		if i == 128 {
+			break
+		}
+		node = node.children[bitAt(ip16, i)]
+	}
+	return match
+}
+
+// prefixBits returns the 16 byte form of the prefix address along with the
+// prefix length in bits, adjusted for IPv4-mapped addresses.
+func prefixBits(prefix *net.IPNet) (net.IP, int) {
+	ones, bits := prefix.Mask.Size()
+	if bits == 32 {
+		ones += 96
+	}
+	return prefix.IP.To16(), ones
+}
+
+// bitAt returns bit i of ip, counting from the most significant bit.
+func bitAt(ip net.IP, i int) int {
+	return int(ip[i/8]>>(7-uint(i%8))) & 1
+}
+
+// parsePrefix parses a CIDR prefix or a bare IP address.
+// Bare addresses are treated as a /32 or /128.
+func parsePrefix(s string) (*net.IPNet, error) {
+	if _, prefix, err := net.ParseCIDR(s); err == nil {
+		return prefix, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, &InvalidIP{IP: s, Msg: "invalid CIDR prefix"}
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
@@ -0,0 +1,245 @@
+package policy
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPrefixTreeLookup(t *testing.T) {
+	type entry struct {
+		prefix string
+		action Action
+	}
+
+	tests := []struct {
+		name    string
+		entries []entry
+		ip      string
+		want    string // matched prefix, empty for no match
+		action  Action
+	}{
+		{
+			name:    "no entries",
+			entries: nil,
+			ip:      "192.0.2.1",
+		},
+		{
+			name:    "ipv4 match",
+			entries: []entry{{"192.0.2.0/24", ActionAct}},
+			ip:      "192.0.2.77",
+			want:    "192.0.2.0/24",
+			action:  ActionAct,
+		},
+		{
+			name:    "ipv4 outside prefix",
+			entries: []entry{{"192.0.2.0/24", ActionAct}},
+			ip:      "192.0.3.1",
+		},
+		{
+			name:    "longest prefix wins",
+			entries: []entry{{"10.0.0.0/8", ActionAct}, {"10.1.0.0/16", ActionAllow}, {"10.1.2.0/24", ActionAct}},
+			ip:      "10.1.2.3",
+			want:    "10.1.2.0/24",
+			action:  ActionAct,
+		},
+		{
+			name:    "longest prefix wins regardless of insert order",
+			entries: []entry{{"10.1.2.0/24", ActionAct}, {"10.1.0.0/16", ActionAllow}, {"10.0.0.0/8", ActionAct}},
+			ip:      "10.1.9.9",
+			want:    "10.1.0.0/16",
+			action:  ActionAllow,
+		},
+		{
+			name:    "falls back to shorter overlapping prefix",
+			entries: []entry{{"10.0.0.0/8", ActionAct}, {"10.1.0.0/16", ActionAllow}},
+			ip:      "10.2.0.1",
+			want:    "10.0.0.0/8",
+			action:  ActionAct,
+		},
+		{
+			name:    "act wins over allow for the same prefix",
+			entries: []entry{{"198.51.100.0/24", ActionAct}, {"198.51.100.0/24", ActionAllow}},
+			ip:      "198.51.100.1",
+			want:    "198.51.100.0/24",
+			action:  ActionAct,
+		},
+		{
+			name:    "act replaces allow for the same prefix",
+			entries: []entry{{"198.51.100.0/24", ActionAllow}, {"198.51.100.0/24", ActionAct}},
+			ip:      "198.51.100.1",
+			want:    "198.51.100.0/24",
+			action:  ActionAct,
+		},
+		{
+			name:    "ipv4 /0 matches every ipv4 address",
+			entries: []entry{{"0.0.0.0/0", ActionAct}},
+			ip:      "203.0.113.9",
+			want:    "0.0.0.0/0",
+			action:  ActionAct,
+		},
+		{
+			name:    "ipv4 /0 does not match ipv6",
+			entries: []entry{{"0.0.0.0/0", ActionAct}},
+			ip:      "2001:db8::1",
+		},
+		{
+			name:    "ipv6 /0 matches ipv6",
+			entries: []entry{{"::/0", ActionAct}},
+			ip:      "2001:db8::1",
+			want:    "::/0",
+			action:  ActionAct,
+		},
+		{
+			name:    "ipv4 /32 matches only the address",
+			entries: []entry{{"192.0.2.1/32", ActionAct}},
+			ip:      "192.0.2.1",
+			want:    "192.0.2.1/32",
+			action:  ActionAct,
+		},
+		{
+			name:    "ipv4 /32 neighbour",
+			entries: []entry{{"192.0.2.1/32", ActionAct}},
+			ip:      "192.0.2.2",
+		},
+		{
+			name:    "bare ipv4 address",
+			entries: []entry{{"192.0.2.1", ActionAct}},
+			ip:      "192.0.2.1",
+			want:    "192.0.2.1/32",
+			action:  ActionAct,
+		},
+		{
+			name:    "ipv6 /128 matches only the address",
+			entries: []entry{{"2001:db8::1/128", ActionAct}},
+			ip:      "2001:db8::1",
+			want:    "2001:db8::1/128",
+			action:  ActionAct,
+		},
+		{
+			name:    "ipv6 /128 neighbour",
+			entries: []entry{{"2001:db8::1/128", ActionAct}},
+			ip:      "2001:db8::2",
+		},
+		{
+			name:    "bare ipv6 address",
+			entries: []entry{{"2001:db8::1", ActionAct}},
+			ip:      "2001:db8::1",
+			want:    "2001:db8::1/128",
+			action:  ActionAct,
+		},
+		{
+			name:    "ipv6 longest prefix",
+			entries: []entry{{"2001:db8::/32", ActionAct}, {"2001:db8:1::/48", ActionAllow}},
+			ip:      "2001:db8:1::5",
+			want:    "2001:db8:1::/48",
+			action:  ActionAllow,
+		},
+		{
+			name:    "ipv4-mapped ipv6 address matches ipv4 prefix",
+			entries: []entry{{"192.0.2.0/24", ActionAct}},
+			ip:      "::ffff:192.0.2.10",
+			want:    "192.0.2.0/24",
+			action:  ActionAct,
+		},
+		{
+			name:    "ipv4 address matches ipv4-mapped ipv6 prefix",
+			entries: []entry{{"::ffff:192.0.2.0/120", ActionAct}},
+			ip:      "192.0.2.10",
+			want:    "192.0.2.0/24",
+			action:  ActionAct,
+		},
+		{
+			name:    "ipv4 prefix does not match ipv6 with the same bits",
+			entries: []entry{{"192.0.2.0/24", ActionAct}},
+			ip:      "c000:200::1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := newPrefixTree()
+			for _, e := range tt.entries {
+				prefix, err := parsePrefix(e.prefix)
+				if err != nil {
+					t.Fatalf("parsePrefix(%q): %v", e.prefix, err)
+				}
+				tree.insert(prefix, e.action)
+			}
+
+			got := tree.lookup(net.ParseIP(tt.ip))
+			if tt.want == "" {
+				if got != nil {
+					t.Fatalf("lookup(%s) = %s, want no match", tt.ip, got.prefix)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("lookup(%s) = no match, want %s", tt.ip, tt.want)
+			}
+			if got.prefix.String() != tt.want {
+				t.Errorf("lookup(%s) prefix = %s, want %s", tt.ip, got.prefix, tt.want)
+			}
+			if got.action != tt.action {
+				t.Errorf("lookup(%s) action = %s, want %s", tt.ip, got.action, tt.action)
+			}
+		})
+	}
+}
+
+func TestPrefixTreeSize(t *testing.T) {
+	tree := newPrefixTree()
+	for _, s := range []string{"10.0.0.0/8", "10.0.0.0/8", "10.0.0.0/16", "2001:db8::/32"} {
+		prefix, err := parsePrefix(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree.insert(prefix, ActionAct)
+	}
+	if tree.size != 3 {
+		t.Errorf("size = %d, want 3", tree.size)
+	}
+}
+
+func TestPrefixTreeLookupInvalidIP(t *testing.T) {
+	tree := newPrefixTree()
+	prefix, _ := parsePrefix("::/0")
+	tree.insert(prefix, ActionAct)
+	if got := tree.lookup(nil); got != nil {
+		t.Errorf("lookup(nil) = %s, want no match", got.prefix)
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "192.0.2.0/24", want: "192.0.2.0/24"},
+		{in: "192.0.2.9/24", want: "192.0.2.0/24"},
+		{in: "192.0.2.1", want: "192.0.2.1/32"},
+		{in: "2001:db8::/32", want: "2001:db8::/32"},
+		{in: "2001:db8::1", want: "2001:db8::1/128"},
+		{in: "192.0.2.0/33", wantErr: true},
+		{in: "example.com", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parsePrefix(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsePrefix(%q) = %s, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePrefix(%q): %v", tt.in, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("parsePrefix(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package policy
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/structs"
+
+	// Embed the timezone database, so policy timezones load on Lambda
+	_ "time/tzdata"
+)
+
+// ScheduleWindow is a weekly window in which a rule applies, in the
+// policy timezone. When End is before Start, the window runs past midnight
+// into the next day, e.g. 22:00 to 06:00. When End equals Start, the window
+// lasts 24 hours.
+type ScheduleWindow struct {
+	// Days the window starts on: mon, tue, wed, thu, fri, sat, sun.
+	// Defaults to every day.
+	Days []string `json:"days"`
+
+	// Start and End are the time of day, as HH:MM.
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// schedule is the compiled form of a rule's not_before, not_after and schedule.
+type schedule struct {
+	notBefore time.Time
+	notAfter  time.Time
+	windows   []scheduleWindow
+	loc       *time.Location
+}
+
+// scheduleWindow is a compiled ScheduleWindow. Times are minutes since midnight.
+type scheduleWindow struct {
+	days  [7]bool
+	start int
+	end   int
+}
+
+// weekdays maps short and long day names to time.Weekday.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// newSchedule compiles the schedule of a rule definition.
+// It returns nil when the rule always applies.
+func newSchedule(def *RuleDefinition, loc *time.Location) (*schedule, error) {
+	if def.NotBefore == "" && def.NotAfter == "" && len(def.Schedule) == 0 {
+		return nil, nil
+	}
+
+	s := &schedule{loc: loc}
+	var err error
+	if def.NotBefore != "" {
+		if s.notBefore, err = parseScheduleTime(def.NotBefore, loc); err != nil {
+			return nil, &InvalidRule{Name: def.Name, Msg: "invalid not_before", Err: err}
+		}
+	}
+	if def.NotAfter != "" {
+		if s.notAfter, err = parseScheduleTime(def.NotAfter, loc); err != nil {
+			return nil, &InvalidRule{Name: def.Name, Msg: "invalid not_after", Err: err}
+		}
+	}
+	if !s.notBefore.IsZero() && !s.notAfter.IsZero() && !s.notAfter.After(s.notBefore) {
+		return nil, &InvalidRule{Name: def.Name, Msg: "not_after must be after not_before"}
+	}
+
+	for i, window := range def.Schedule {
+		w := scheduleWindow{}
+		if w.start, err = parseTimeOfDay(window.Start); err != nil {
+			return nil, &InvalidRule{Name: def.Name, Msg: "invalid start for schedule window " + strconv.Itoa(i+1), Err: err}
+		}
+		if w.end, err = parseTimeOfDay(window.End); err != nil {
+			return nil, &InvalidRule{Name: def.Name, Msg: "invalid end for schedule window " + strconv.Itoa(i+1), Err: err}
+		}
+		if len(window.Days) == 0 {
+			w.days = [7]bool{true, true, true, true, true, true, true}
+		}
+		for _, day := range window.Days {
+			weekday, ok := weekdays[strings.ToLower(strings.TrimSpace(day))]
+			if !ok {
+				return nil, &InvalidRule{Name: def.Name, Msg: "invalid day '" + day + "' for schedule window " + strconv.Itoa(i+1)}
+			}
+			w.days[weekday] = true
+		}
+		s.windows = append(s.windows, w)
+	}
+	return s, nil
+}
+
+// active returns an empty string if the rule applies at t,
+// or the reason it does not.
+func (s *schedule) active(t time.Time) string {
+	if !s.notBefore.IsZero() && t.Before(s.notBefore) {
+		return "rule is not active before " + s.notBefore.Format(time.RFC3339)
+	}
+	if !s.notAfter.IsZero() && !t.Before(s.notAfter) {
+		return "rule expired at " + s.notAfter.Format(time.RFC3339)
+	}
+	if len(s.windows) == 0 {
+		return ""
+	}
+
+	local := t.In(s.loc)
+	minute := local.Hour()*60 + local.Minute()
+	today := local.Weekday()
+	yesterday := (today + 6) % 7
+	for _, w := range s.windows {
+		if w.start < w.end {
+			if w.days[today] && minute >= w.start && minute < w.end {
+				return ""
+			}
+			continue
+		}
+
+		// The window runs past midnight, or lasts 24 hours
+		if w.days[today] && minute >= w.start {
+			return ""
+		}
+		if w.days[yesterday] && minute < w.end {
+			return ""
+		}
+	}
+	return "outside the rule schedule"
+}
+
+// parseScheduleTime parses an RFC 3339 time. Times without a
+// UTC offset, or dates alone, are in the policy timezone.
+func parseScheduleTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	_, err := time.Parse(time.RFC3339, value)
+	return time.Time{}, err
+}
+
+// parseTimeOfDay parses HH:MM into minutes since midnight.
+func parseTimeOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// eventTime returns the time of the event, falling back to the account
+// creation time and then the current time.
+func eventTime(event *structs.AccoutCreatedEvent) time.Time {
+	for _, t := range []time.Time{event.CreatedAt.Time, event.Object.CreatedAt.Time} {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return time.Now()
+}
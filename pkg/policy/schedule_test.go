@@ -0,0 +1,196 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+// at returns the time in loc. 2024-01-01 is a Monday.
+func at(loc *time.Location, day int, hour int, minute int) time.Time {
+	return time.Date(2024, 1, day, hour, minute, 0, 0, loc)
+}
+
+func TestScheduleWindows(t *testing.T) {
+	const (
+		mon = 1
+		tue = 2
+		fri = 5
+		sat = 6
+		sun = 7
+	)
+
+	tests := []struct {
+		name    string
+		windows []ScheduleWindow
+		t       time.Time
+		want    bool
+	}{
+		// Same day windows
+		{"inside", []ScheduleWindow{{Start: "09:00", End: "17:00"}}, at(time.UTC, mon, 12, 0), true},
+		{"at start", []ScheduleWindow{{Start: "09:00", End: "17:00"}}, at(time.UTC, mon, 9, 0), true},
+		{"at end", []ScheduleWindow{{Start: "09:00", End: "17:00"}}, at(time.UTC, mon, 17, 0), false},
+		{"a minute before end", []ScheduleWindow{{Start: "09:00", End: "17:00"}}, at(time.UTC, mon, 16, 59), true},
+		{"before start", []ScheduleWindow{{Start: "09:00", End: "17:00"}}, at(time.UTC, mon, 8, 59), false},
+
+		// Windows that cross midnight
+		{"overnight, evening", []ScheduleWindow{{Start: "22:00", End: "06:00"}}, at(time.UTC, mon, 23, 30), true},
+		{"overnight, at start", []ScheduleWindow{{Start: "22:00", End: "06:00"}}, at(time.UTC, mon, 22, 0), true},
+		{"overnight, midnight", []ScheduleWindow{{Start: "22:00", End: "06:00"}}, at(time.UTC, tue, 0, 0), true},
+		{"overnight, early morning", []ScheduleWindow{{Start: "22:00", End: "06:00"}}, at(time.UTC, tue, 5, 59), true},
+		{"overnight, at end", []ScheduleWindow{{Start: "22:00", End: "06:00"}}, at(time.UTC, tue, 6, 0), false},
+		{"overnight, daytime", []ScheduleWindow{{Start: "22:00", End: "06:00"}}, at(time.UTC, tue, 12, 0), false},
+		{"ending at midnight", []ScheduleWindow{{Start: "18:00", End: "00:00"}}, at(time.UTC, mon, 23, 59), true},
+		{"ending at midnight, after", []ScheduleWindow{{Start: "18:00", End: "00:00"}}, at(time.UTC, tue, 0, 0), false},
+
+		// Start equal to end lasts 24 hours
+		{"24 hours, at start", []ScheduleWindow{{Days: []string{"mon"}, Start: "09:00", End: "09:00"}}, at(time.UTC, mon, 9, 0), true},
+		{"24 hours, before start", []ScheduleWindow{{Days: []string{"mon"}, Start: "09:00", End: "09:00"}}, at(time.UTC, mon, 8, 59), false},
+		{"24 hours, next morning", []ScheduleWindow{{Days: []string{"mon"}, Start: "09:00", End: "09:00"}}, at(time.UTC, tue, 8, 59), true},
+		{"24 hours, at end", []ScheduleWindow{{Days: []string{"mon"}, Start: "09:00", End: "09:00"}}, at(time.UTC, tue, 9, 0), false},
+		{"whole day", []ScheduleWindow{{Days: []string{"sat"}, Start: "00:00", End: "00:00"}}, at(time.UTC, sat, 23, 59), true},
+		{"whole day, next day", []ScheduleWindow{{Days: []string{"sat"}, Start: "00:00", End: "00:00"}}, at(time.UTC, sun, 0, 0), false},
+		{"whole day, day before", []ScheduleWindow{{Days: []string{"sat"}, Start: "00:00", End: "00:00"}}, at(time.UTC, fri, 23, 59), false},
+		{"every day, all day", []ScheduleWindow{{Start: "00:00", End: "00:00"}}, at(time.UTC, tue, 13, 0), true},
+
+		// Weekday filters
+		{"weekday", []ScheduleWindow{{Days: []string{"mon", "tue"}, Start: "09:00", End: "17:00"}}, at(time.UTC, tue, 10, 0), true},
+		{"other weekday", []ScheduleWindow{{Days: []string{"mon", "tue"}, Start: "09:00", End: "17:00"}}, at(time.UTC, fri, 10, 0), false},
+		{"long day names", []ScheduleWindow{{Days: []string{"Saturday", " SUNDAY "}, Start: "09:00", End: "17:00"}}, at(time.UTC, sun, 10, 0), true},
+
+		// Weekday filters across midnight use the day the window starts
+		{"friday night, friday", []ScheduleWindow{{Days: []string{"fri"}, Start: "22:00", End: "06:00"}}, at(time.UTC, fri, 23, 0), true},
+		{"friday night, saturday morning", []ScheduleWindow{{Days: []string{"fri"}, Start: "22:00", End: "06:00"}}, at(time.UTC, sat, 3, 0), true},
+		{"friday night, friday morning", []ScheduleWindow{{Days: []string{"fri"}, Start: "22:00", End: "06:00"}}, at(time.UTC, fri, 3, 0), false},
+		{"friday night, saturday night", []ScheduleWindow{{Days: []string{"fri"}, Start: "22:00", End: "06:00"}}, at(time.UTC, sat, 23, 0), false},
+		{"sunday night, monday morning", []ScheduleWindow{{Days: []string{"sun"}, Start: "22:00", End: "06:00"}}, at(time.UTC, 8, 2, 0), true},
+
+		// Several windows
+		{"second window", []ScheduleWindow{{Start: "09:00", End: "10:00"}, {Start: "14:00", End: "15:00"}}, at(time.UTC, mon, 14, 30), true},
+		{"between windows", []ScheduleWindow{{Start: "09:00", End: "10:00"}, {Start: "14:00", End: "15:00"}}, at(time.UTC, mon, 12, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newSchedule(&RuleDefinition{Name: "scheduled", Schedule: tt.windows}, time.UTC)
+			if err != nil || s == nil {
+				t.Fatalf("newSchedule() = %v, %v, want a schedule", s, err)
+			}
+			reason := s.active(tt.t)
+			if (reason == "") != tt.want {
+				t.Errorf("active(%s) = %q, want active %v", tt.t.Format(time.RFC3339), reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleTimezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overnight := []ScheduleWindow{{Days: []string{"mon"}, Start: "22:00", End: "06:00"}}
+
+	tests := []struct {
+		name    string
+		loc     *time.Location
+		windows []ScheduleWindow
+		t       time.Time
+		want    bool
+	}{
+		// 2024-01-02 03:00 UTC is Monday 22:00 in New York (UTC-5)
+		{"utc converted to policy time", newYork, overnight, time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC), true},
+		{"utc day differs from policy day", newYork, overnight, time.Date(2024, 1, 2, 11, 0, 0, 0, time.UTC), false},
+		// 2024-01-01 13:00 UTC is Monday 22:00 in Tokyo (UTC+9)
+		{"ahead of utc", tokyo, overnight, time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC), true},
+		{"ahead of utc, before start", tokyo, overnight, time.Date(2024, 1, 1, 12, 59, 0, 0, time.UTC), false},
+		{"event time in another zone", tokyo, overnight, time.Date(2024, 1, 1, 8, 0, 0, 0, newYork), true},
+		// Daylight saving time: 09:00-17:00 in New York is 14:00 UTC in winter, 13:00 UTC in summer
+		{"winter", newYork, []ScheduleWindow{{Start: "09:00", End: "17:00"}}, time.Date(2024, 1, 15, 13, 30, 0, 0, time.UTC), false},
+		{"summer", newYork, []ScheduleWindow{{Start: "09:00", End: "17:00"}}, time.Date(2024, 7, 15, 13, 30, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newSchedule(&RuleDefinition{Name: "scheduled", Schedule: tt.windows}, tt.loc)
+			if err != nil || s == nil {
+				t.Fatalf("newSchedule() = %v, %v, want a schedule", s, err)
+			}
+			reason := s.active(tt.t)
+			if (reason == "") != tt.want {
+				t.Errorf("active(%s) = %q, want active %v", tt.t.Format(time.RFC3339), reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNotBeforeNotAfter(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		notBefore string
+		notAfter  string
+		t         time.Time
+		want      bool
+	}{
+		{"before not_before", "2024-06-01T00:00:00Z", "", time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC), false},
+		{"at not_before", "2024-06-01T00:00:00Z", "", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), true},
+		{"before not_after", "", "2024-06-01T00:00:00Z", time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC), true},
+		{"at not_after", "", "2024-06-01T00:00:00Z", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), false},
+		{"with an offset", "2024-06-01T00:00:00+02:00", "", time.Date(2024, 5, 31, 22, 0, 0, 0, time.UTC), true},
+		{"date in the policy timezone", "2024-06-01", "", time.Date(2024, 6, 1, 3, 59, 0, 0, time.UTC), false},
+		{"date in the policy timezone, after", "2024-06-01", "", time.Date(2024, 6, 1, 4, 0, 0, 0, time.UTC), true},
+		{"local time in the policy timezone", "2024-06-01T09:30", "", time.Date(2024, 6, 1, 13, 30, 0, 0, time.UTC), true},
+		{"between", "2024-06-01", "2024-06-02", time.Date(2024, 6, 1, 12, 0, 0, 0, newYork), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newSchedule(&RuleDefinition{Name: "scheduled", NotBefore: tt.notBefore, NotAfter: tt.notAfter}, newYork)
+			if err != nil || s == nil {
+				t.Fatalf("newSchedule() = %v, %v, want a schedule", s, err)
+			}
+			reason := s.active(tt.t)
+			if (reason == "") != tt.want {
+				t.Errorf("active(%s) = %q, want active %v", tt.t.Format(time.RFC3339), reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		def  *RuleDefinition
+	}{
+		{"bad start", &RuleDefinition{Schedule: []ScheduleWindow{{Start: "9am", End: "17:00"}}}},
+		{"bad end", &RuleDefinition{Schedule: []ScheduleWindow{{Start: "09:00", End: "24:00"}}}},
+		{"missing end", &RuleDefinition{Schedule: []ScheduleWindow{{Start: "09:00"}}}},
+		{"bad day", &RuleDefinition{Schedule: []ScheduleWindow{{Days: []string{"funday"}, Start: "09:00", End: "17:00"}}}},
+		{"bad not_before", &RuleDefinition{NotBefore: "tomorrow"}},
+		{"bad not_after", &RuleDefinition{NotAfter: "2024-13-01"}},
+		{"not_after before not_before", &RuleDefinition{NotBefore: "2024-06-02", NotAfter: "2024-06-01"}},
+		{"not_after equal to not_before", &RuleDefinition{NotBefore: "2024-06-01", NotAfter: "2024-06-01"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.def.Name = "scheduled"
+			if _, err := newSchedule(tt.def, time.UTC); err == nil {
+				t.Errorf("newSchedule() succeeded, want an error")
+			}
+		})
+	}
+
+	if s, err := newSchedule(&RuleDefinition{Name: "always"}, time.UTC); s != nil || err != nil {
+		t.Errorf("newSchedule() without a schedule = %v, %v, want nil", s, err)
+	}
+}
@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"sort"
+	"strconv"
+	"text/template"
+
+	"github.com/rmrfslashbin/mastoban/pkg/mastoclient"
+)
+
+// ScoreRule is the rule name recorded on decisions reached by the risk score.
+const ScoreRule = "risk-score"
+
+// Signal is a weighted rule that matched, and the weight it added to the risk score.
+type Signal struct {
+	Rule   string `json:"rule"`
+	Weight int    `json:"weight"`
+	Reason string `json:"reason"`
+	Match  string `json:"match,omitempty"`
+
+	// DryRun is set for dry run rules, whose weight is not counted.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// Threshold maps a risk score to an action. The highest threshold
+// at or below the score applies.
+type Threshold struct {
+	// Score is the minimum risk score for the threshold.
+	Score int `json:"score"`
+
+	// Level is the suspend level to apply: sensitive, disable, silence or suspend.
+	Level string `json:"level"`
+
+	// Action is "act" (the default), "hold" or "reject".
+	Action Action `json:"action,omitempty"`
+
+	// Text is the message sent with the suspension, as a text/template
+	// executed with TextData. Defaults to MASTODON_SUSPEND_TEXT.
+	Text string `json:"text,omitempty"`
+
+	// DryRun records the decision the threshold would have made
+	// instead of enforcing it. Lower thresholds still apply.
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// text is the parsed Text template.
+	text *template.Template
+}
+
+// action returns the threshold action, defaulting to ActionAct.
+func (t *Threshold) action() Action {
+	if t.Action == "" {
+		return ActionAct
+	}
+	return t.Action
+}
+
+// validateThresholds checks the thresholds and sorts them by score, highest first.
+func (p *Policy) validateThresholds() error {
+	seen := make(map[int]struct{})
+	for i := range p.Thresholds {
+		t := &p.Thresholds[i]
+		score := strconv.Itoa(t.Score)
+
+		if t.Score <= 0 {
+			return &InvalidPolicy{Msg: "threshold score must be greater than zero: " + score}
+		}
+		if _, ok := seen[t.Score]; ok {
+			return &InvalidPolicy{Msg: "duplicate threshold score: " + score}
+		}
+		seen[t.Score] = struct{}{}
+
+		switch t.action() {
+		case ActionAct:
+			if !mastoclient.ValidSuspendLevel(t.Level) {
+				return &InvalidPolicy{Msg: "invalid level '" + t.Level + "' for threshold " + score}
+			}
+		case ActionHold, ActionReject:
+		default:
+			return &InvalidPolicy{Msg: "invalid action '" + string(t.Action) + "' for threshold " + score}
+		}
+
+		if t.Text != "" {
+			tmpl, err := parseText(ScoreRule, t.Text)
+			if err != nil {
+				return &InvalidPolicy{Msg: "invalid text template for threshold " + score, Err: err}
+			}
+			t.text = tmpl
+		}
+	}
+
+	sort.Slice(p.Thresholds, func(i, j int) bool {
+		return p.Thresholds[i].Score > p.Thresholds[j].Score
+	})
+	return nil
+}
+
+// evaluateThresholds returns the decision of the highest threshold
+// reached by the risk score, skipping dry run thresholds, or nil.
+func (p *Policy) evaluateThresholds(ev *evaluation) *Decision {
+	for i := range p.Thresholds {
+		t := &p.Thresholds[i]
+		if ev.score < t.Score {
+			continue
+		}
+
+		decision := ev.withText(t.text, ev.decision(&Decision{
+			Action: t.action(),
+			Level:  t.Level,
+			Rule:   ScoreRule,
+			Reason: "risk score " + strconv.Itoa(ev.score) + " reached the threshold of " + strconv.Itoa(t.Score),
+		}))
+		ev.outcome(decision, t.DryRun)
+
+		if t.DryRun && decision.Action != ActionError {
+			ev.dryRun(decision)
+			continue
+		}
+		return decision
+	}
+	return nil
+}
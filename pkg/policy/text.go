@@ -0,0 +1,77 @@
+package policy
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/rmrfslashbin/mastoban/pkg/structs"
+)
+
+// TextData is the data available to suspend text templates, e.g.
+//
+//	Account {{.Account.Username}} from {{.Country}} was suspended by rule {{.Rule}}.
+type TextData struct {
+	// Account is the account being acted on.
+	Account structs.EventObject
+
+	// IP is the account IP address.
+	IP string
+
+	// Country and Continent are the GeoIP codes for IP.
+	// They are empty when a rule acts before the GeoIP lookup.
+	Country   string
+	Continent string
+
+	// ASN and ASOrganization describe the autonomous system of IP.
+	ASN            uint
+	ASOrganization string
+
+	// Rule, Reason and Match describe the rule that acted.
+	Rule   string
+	Reason string
+	Match  string
+
+	// Level is the suspend level being applied.
+	Level string
+
+	// Score is the account's risk score.
+	Score int
+}
+
+// parseText parses a suspend text template. The template is executed
+// against empty data so unknown fields are reported when the policy loads.
+func parseText(name string, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, &TextData{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// renderText renders a suspend text template for the decision.
+func renderText(tmpl *template.Template, d *Decision) (string, error) {
+	data := &TextData{
+		Account: d.Input.Event.Object,
+		IP:      d.Input.IP.String(),
+		Rule:    d.Rule,
+		Reason:  d.Reason,
+		Match:   d.Match,
+		Level:   d.Level,
+		Score:   d.Score,
+	}
+	if d.Input.GeoIP != nil {
+		data.Country = d.Input.GeoIP.Country
+		data.Continent = d.Input.GeoIP.Continent
+		data.ASN = d.Input.GeoIP.ASN
+		data.ASOrganization = d.Input.GeoIP.ASOrganization
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
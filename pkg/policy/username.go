@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// UsernameParams configures a UsernameRule.
+type UsernameParams struct {
+	// Patterns lists regular expressions matched against the username.
+	// Use the (?i) flag for case-insensitive patterns.
+	Patterns []string `json:"patterns"`
+
+	// Reserved lists names that may not be used as a word in a username.
+	// The username is split on separators and digits, so "admin" matches
+	// "admin1" and "Admin_Team", but not "administrator".
+	Reserved []string `json:"reserved"`
+
+	// Protected lists names to protect from impersonation, such as the
+	// usernames of moderators. A username matches when its confusable
+	// skeleton is the same as that of a protected name (e.g. "mod_аlice"
+	// with a Cyrillic "а", or "m0d_alice").
+	Protected []string `json:"protected"`
+}
+
+// UsernameRule acts on accounts by their username.
+type UsernameRule struct {
+	name      string
+	patterns  []*regexp.Regexp
+	reserved  map[string]struct{}
+	protected map[string]string
+}
+
+// NewUsernameRule creates a new UsernameRule.
+func NewUsernameRule(name string, params *UsernameParams) (*UsernameRule, error) {
+	r := &UsernameRule{
+		name:      name,
+		reserved:  make(map[string]struct{}),
+		protected: make(map[string]string),
+	}
+
+	for _, pattern := range params.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, &InvalidRule{Name: name, Msg: "invalid pattern '" + pattern + "'", Err: err}
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	for _, reserved := range params.Reserved {
+		if reserved = strings.ToLower(strings.TrimSpace(reserved)); reserved != "" {
+			r.reserved[reserved] = struct{}{}
+		}
+	}
+	for _, protected := range params.Protected {
+		if skeleton := Skeleton(protected); skeleton != "" {
+			r.protected[skeleton] = protected
+		}
+	}
+
+	if len(r.patterns) == 0 && len(r.reserved) == 0 && len(r.protected) == 0 {
+		return nil, &InvalidRule{Name: name, Msg: "no patterns, reserved or protected names"}
+	}
+	return r, nil
+}
+
+// Name returns the name of the rule.
+func (r *UsernameRule) Name() string {
+	return r.name
+}
+
+// Evaluate acts on accounts whose username impersonates a protected
+// name, uses a reserved name, or matches a pattern.
+func (r *UsernameRule) Evaluate(in *Input) (*Result, error) {
+	username := in.Event.Object.Username
+	if username == "" {
+		return nil, nil
+	}
+
+	if protected, ok := r.protected[Skeleton(username)]; ok {
+		return &Result{
+			Action: ActionAct,
+			Reason: "username '" + username + "' is confusable with the protected name '" + protected + "'",
+			Match:  protected,
+		}, nil
+	}
+
+	for _, word := range usernameWords(username) {
+		if _, ok := r.reserved[word]; ok {
+			return &Result{
+				Action: ActionAct,
+				Reason: "username '" + username + "' uses the reserved name '" + word + "'",
+				Match:  word,
+			}, nil
+		}
+	}
+
+	for _, re := range r.patterns {
+		if re.MatchString(username) {
+			return &Result{
+				Action: ActionAct,
+				Reason: "username '" + username + "' matches the pattern '" + re.String() + "'",
+				Match:  re.String(),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// usernameWords splits a lower-cased username into words on separators and digits.
+func usernameWords(username string) []string {
+	return strings.FieldsFunc(strings.ToLower(username), func(c rune) bool {
+		return !unicode.IsLetter(c)
+	})
+}
@@ -0,0 +1,213 @@
+package policy
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/velocity"
+)
+
+// Velocity rule keys
+const (
+	VelocityKeyIP          = "ip"
+	VelocityKeyPrefix      = "prefix"
+	VelocityKeyASN         = "asn"
+	VelocityKeyEmailDomain = "email_domain"
+)
+
+// VelocityParams configures a VelocityRule.
+//
+// The engine counts each signup once, against every key used by the
+// policy's velocity rules, before the rules are evaluated.
+type VelocityParams struct {
+	// Key is what signups are counted by: ip, prefix, asn or email_domain.
+	Key string `json:"key"`
+
+	// IPv4Prefix and IPv6Prefix set the network size counted by the prefix key.
+	// They default to 24 and 64.
+	IPv4Prefix int `json:"ipv4_prefix"`
+	IPv6Prefix int `json:"ipv6_prefix"`
+
+	// Window is how far back to count signups, e.g. "10m" or "1h".
+	Window string `json:"window"`
+
+	// Threshold is the number of signups within the window, including
+	// the one being evaluated, at which the rule acts.
+	Threshold int `json:"threshold"`
+}
+
+// VelocityRule acts on accounts when too many signups share
+// an IP address, network, ASN or email domain within a window.
+type VelocityRule struct {
+	name       string
+	key        string
+	ipv4Prefix int
+	ipv6Prefix int
+	window     time.Duration
+	threshold  int
+}
+
+// NewVelocityRule creates a new VelocityRule.
+func NewVelocityRule(name string, params *VelocityParams) (*VelocityRule, error) {
+	r := &VelocityRule{
+		name:       name,
+		key:        params.Key,
+		ipv4Prefix: params.IPv4Prefix,
+		ipv6Prefix: params.IPv6Prefix,
+		threshold:  params.Threshold,
+	}
+
+	switch r.key {
+	case VelocityKeyIP, VelocityKeyPrefix, VelocityKeyASN, VelocityKeyEmailDomain:
+	default:
+		return nil, &InvalidRule{Name: name, Msg: "invalid velocity key '" + r.key + "'"}
+	}
+
+	if r.ipv4Prefix == 0 {
+		r.ipv4Prefix = 24
+	}
+	if r.ipv6Prefix == 0 {
+		r.ipv6Prefix = 64
+	}
+	if r.ipv4Prefix < 1 || r.ipv4Prefix > 32 || r.ipv6Prefix < 1 || r.ipv6Prefix > 128 {
+		return nil, &InvalidRule{Name: name, Msg: "invalid prefix length"}
+	}
+
+	window, err := time.ParseDuration(params.Window)
+	if err != nil {
+		return nil, &InvalidRule{Name: name, Msg: "invalid window", Err: err}
+	}
+	if window <= 0 {
+		return nil, &InvalidRule{Name: name, Msg: "window must be greater than zero"}
+	}
+	r.window = window
+
+	if r.threshold < 1 {
+		return nil, &InvalidRule{Name: name, Msg: "threshold must be greater than zero"}
+	}
+	return r, nil
+}
+
+// Name returns the name of the rule.
+func (r *VelocityRule) Name() string {
+	return r.name
+}
+
+// Key returns the counter key for the input, or an empty
+// string when the input has nothing to count by.
+func (r *VelocityRule) Key(in *Input) string {
+	switch r.key {
+	case VelocityKeyIP:
+		return velocity.IPKey(in.IP)
+	case VelocityKeyPrefix:
+		return velocity.PrefixKey(in.IP, r.ipv4Prefix, r.ipv6Prefix)
+	case VelocityKeyASN:
+		if in.GeoIP == nil || in.GeoIP.ASN == 0 {
+			return ""
+		}
+		return velocity.ASNKey(in.GeoIP.ASN)
+	case VelocityKeyEmailDomain:
+		domain := EmailDomain(in.Event.Object.Email)
+		if domain == "" {
+			return ""
+		}
+		return velocity.EmailDomainKey(domain)
+	}
+	return ""
+}
+
+// Window returns how far back the rule counts signups.
+func (r *VelocityRule) Window() time.Duration {
+	return r.window
+}
+
+// Evaluate acts on accounts when the number of signups
+// within the window reaches the threshold.
+func (r *VelocityRule) Evaluate(in *Input) (*Result, error) {
+	key := r.Key(in)
+	if key == "" {
+		return nil, nil
+	}
+
+	count, ok := in.VelocityCount(key, r.window)
+	if !ok || count < r.threshold {
+		return nil, nil
+	}
+
+	subject := velocity.Subject(key)
+	return &Result{
+		Action: ActionAct,
+		Reason: strconv.Itoa(count) + " signups from " + r.key + " " + subject + " within " + r.window.String() +
+			" (threshold " + strconv.Itoa(r.threshold) + ")",
+		Match: subject,
+	}, nil
+}
+
+// VelocityCount is the number of recent signups counted against a key.
+type VelocityCount struct {
+	Key    string
+	Window time.Duration
+	Count  int
+}
+
+// VelocityCount returns the number of signups counted against the key within
+// the window. It returns false when the engine did not count the key.
+func (in *Input) VelocityCount(key string, window time.Duration) (int, bool) {
+	for _, count := range in.Velocity {
+		if count.Key == key && count.Window == window {
+			return count.Count, true
+		}
+	}
+	return 0, false
+}
+
+// observeVelocity records the signup against every key used by the velocity
+// rules, then counts the signups within each rule's window of it. Only the
+// account IP is counted, not the addresses in its history. Signups are
+// recorded at the event time under the account ID, so a redelivered event
+// is not counted twice.
+func (e *Engine) observeVelocity(in *Input) error {
+	at := in.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	keys := []string{}
+	ttls := make(map[string]time.Duration)
+	for _, rule := range e.rules {
+		r, ok := unwrapRule(rule).(*VelocityRule)
+		if !ok {
+			continue
+		}
+		key := r.Key(in)
+		if key == "" {
+			continue
+		}
+		if _, ok := ttls[key]; !ok {
+			keys = append(keys, key)
+		}
+		if r.window > ttls[key] {
+			ttls[key] = r.window
+		}
+		if _, ok := in.VelocityCount(key, r.window); !ok {
+			in.Velocity = append(in.Velocity, VelocityCount{Key: key, Window: r.window})
+		}
+	}
+
+	if !e.velocityReadOnly {
+		for _, key := range keys {
+			if err := e.velocity.Record(key, in.Event.Object.Id, at, ttls[key]); err != nil {
+				return &VelocityFailed{Key: key, Err: err}
+			}
+		}
+	}
+
+	for i := range in.Velocity {
+		count, err := e.velocity.Count(in.Velocity[i].Key, at.Add(-in.Velocity[i].Window))
+		if err != nil {
+			return &VelocityFailed{Key: in.Velocity[i].Key, Err: err}
+		}
+		in.Velocity[i].Count = count
+	}
+	return nil
+}
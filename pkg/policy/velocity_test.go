@@ -0,0 +1,221 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/mastoban/pkg/velocity"
+)
+
+// recordingStore is a velocity.MemoryStore that keeps the TTL of each Record call.
+type recordingStore struct {
+	*velocity.MemoryStore
+	records map[string][]time.Duration
+}
+
+func newRecordingStore() *recordingStore {
+	return &recordingStore{MemoryStore: velocity.NewMemoryStore(), records: make(map[string][]time.Duration)}
+}
+
+func (s *recordingStore) Record(key string, id string, at time.Time, ttl time.Duration) error {
+	s.records[key] = append(s.records[key], ttl)
+	return s.MemoryStore.Record(key, id, at, ttl)
+}
+
+// newVelocityTestRule creates a velocity rule or fails the test.
+func newVelocityTestRule(t *testing.T, params *VelocityParams) *VelocityRule {
+	t.Helper()
+	r, err := NewVelocityRule("velocity-"+params.Key, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestVelocityRuleKey(t *testing.T) {
+	noGeoIP := newTestInput("1", "192.0.2.9")
+	noGeoIP.GeoIP = nil
+
+	noEmail := newTestInput("1", "192.0.2.9")
+	noEmail.Event.Object.Email = ""
+
+	tests := []struct {
+		name   string
+		params *VelocityParams
+		in     *Input
+		want   string
+	}{
+		{"ip", &VelocityParams{Key: "ip", Window: "1m", Threshold: 1}, newTestInput("1", "192.0.2.9"), "ip:192.0.2.9"},
+		{"default ipv4 prefix", &VelocityParams{Key: "prefix", Window: "1m", Threshold: 1}, newTestInput("1", "192.0.2.9"), "prefix:192.0.2.0/24"},
+		{"default ipv6 prefix", &VelocityParams{Key: "prefix", Window: "1m", Threshold: 1}, newTestInput("1", "2001:db8:1:2::9"), "prefix:2001:db8:1:2::/64"},
+		{"ipv4 prefix", &VelocityParams{Key: "prefix", IPv4Prefix: 16, Window: "1m", Threshold: 1}, newTestInput("1", "192.0.2.9"), "prefix:192.0.0.0/16"},
+		{"ipv6 prefix", &VelocityParams{Key: "prefix", IPv6Prefix: 48, Window: "1m", Threshold: 1}, newTestInput("1", "2001:db8:1:2::9"), "prefix:2001:db8:1::/48"},
+		{"asn", &VelocityParams{Key: "asn", Window: "1m", Threshold: 1}, newTestInput("1", "192.0.2.9"), "asn:AS14061"},
+		{"email domain", &VelocityParams{Key: "email_domain", Window: "1m", Threshold: 1}, newTestInput("1", "192.0.2.9"), "email_domain:example.com"},
+		{"asn without geoip", &VelocityParams{Key: "asn", Window: "1m", Threshold: 1}, noGeoIP, ""},
+		{"email domain without an email", &VelocityParams{Key: "email_domain", Window: "1m", Threshold: 1}, noEmail, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newVelocityTestRule(t, tt.params).Key(tt.in); got != tt.want {
+				t.Errorf("Key() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObserveVelocityWindow(t *testing.T) {
+	store := newRecordingStore()
+	e := &Engine{
+		rules:    []Rule{newVelocityTestRule(t, &VelocityParams{Key: "prefix", Window: "10m", Threshold: 3})},
+		velocity: store,
+	}
+
+	now := time.Now()
+	signups := []struct {
+		id   string
+		ip   string
+		at   time.Time
+		want int
+	}{
+		{"1", "192.0.2.1", now.Add(-30 * time.Minute), 1},
+		{"2", "192.0.2.2", now.Add(-8 * time.Minute), 1}, // the first signup is out of the window
+		{"3", "192.0.2.3", now.Add(-5 * time.Minute), 2},
+		{"4", "198.51.100.1", now.Add(-4 * time.Minute), 1}, // another network
+		{"5", "192.0.2.4", now, 3},
+		{"6", "192.0.2.5", now.Add(3 * time.Minute), 3}, // the second signup has left the window
+	}
+
+	for _, signup := range signups {
+		in := newTestInput(signup.id, signup.ip)
+		in.Time = signup.at
+		if err := e.observeVelocity(in); err != nil {
+			t.Fatal(err)
+		}
+		if len(in.Velocity) != 1 {
+			t.Fatalf("signup %s: %d counts, want 1", signup.id, len(in.Velocity))
+		}
+		if in.Velocity[0].Count != signup.want {
+			t.Errorf("signup %s: count = %d, want %d", signup.id, in.Velocity[0].Count, signup.want)
+		}
+	}
+}
+
+func TestObserveVelocityOncePerAccount(t *testing.T) {
+	store := newRecordingStore()
+	e := &Engine{
+		rules: []Rule{
+			newVelocityTestRule(t, &VelocityParams{Key: "ip", Window: "10m", Threshold: 3}),
+			newVelocityTestRule(t, &VelocityParams{Key: "ip", Window: "1h", Threshold: 10}),
+			newVelocityTestRule(t, &VelocityParams{Key: "asn", Window: "5m", Threshold: 50}),
+		},
+		velocity: store,
+	}
+
+	// Signups are evaluated in order
+	at := time.Now()
+	signups := []struct {
+		name string
+		id   string
+		want int
+	}{
+		{"first signup", "1", 1},
+		{"redelivered event", "1", 1},
+		{"another account", "2", 2},
+	}
+
+	for _, signup := range signups {
+		in := newTestInput(signup.id, "192.0.2.1")
+		in.Time = at
+		if err := e.observeVelocity(in); err != nil {
+			t.Fatal(err)
+		}
+
+		// Each rule's window is counted
+		for _, window := range []time.Duration{10 * time.Minute, time.Hour} {
+			if count, ok := in.VelocityCount("ip:192.0.2.1", window); !ok || count != signup.want {
+				t.Errorf("%s: VelocityCount(ip, %s) = %d, %v, want %d", signup.name, window, count, ok, signup.want)
+			}
+		}
+	}
+
+	// Rules sharing a key record each signup once, kept for the longest window
+	if got := store.records["ip:192.0.2.1"]; len(got) != 3 || got[0] != time.Hour {
+		t.Errorf("ip records = %v, want one per signup for 1h", got)
+	}
+	if got := store.records["asn:AS14061"]; len(got) != 3 || got[0] != 5*time.Minute {
+		t.Errorf("asn records = %v, want one per signup for 5m", got)
+	}
+}
+
+func TestObserveVelocityReadOnly(t *testing.T) {
+	store := newRecordingStore()
+	rules := []Rule{newVelocityTestRule(t, &VelocityParams{Key: "ip", Window: "10m", Threshold: 2})}
+	active := &Engine{rules: rules, velocity: store}
+	shadow := &Engine{rules: rules, velocity: store, velocityReadOnly: true}
+
+	at := time.Now()
+	for _, id := range []string{"1", "2"} {
+		// The worker evaluates each account with the active engine, then the shadow engine
+		in := newTestInput(id, "192.0.2.1")
+		in.Time = at
+		if err := active.observeVelocity(in); err != nil {
+			t.Fatal(err)
+		}
+		shadowIn := newTestInput(id, "192.0.2.1")
+		shadowIn.Time = at
+		if err := shadow.observeVelocity(shadowIn); err != nil {
+			t.Fatal(err)
+		}
+
+		// The shadow engine sees the same count as the active engine
+		if in.Velocity[0].Count != shadowIn.Velocity[0].Count {
+			t.Errorf("account %s: shadow count = %d, active count = %d", id, shadowIn.Velocity[0].Count, in.Velocity[0].Count)
+		}
+	}
+
+	if got := len(store.records["ip:192.0.2.1"]); got != 2 {
+		t.Errorf("recorded %d signups, want 2 (the shadow engine must not record)", got)
+	}
+
+	// A read-only engine on its own counts nothing new
+	in := newTestInput("3", "203.0.113.1")
+	if err := shadow.observeVelocity(in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Velocity[0].Count != 0 {
+		t.Errorf("read-only count = %d, want 0", in.Velocity[0].Count)
+	}
+}
+
+func TestVelocityRuleEvaluate(t *testing.T) {
+	r := newVelocityTestRule(t, &VelocityParams{Key: "prefix", Window: "10m", Threshold: 3})
+
+	tests := []struct {
+		name     string
+		velocity []VelocityCount
+		want     bool
+	}{
+		{"below threshold", []VelocityCount{{Key: "prefix:192.0.2.0/24", Window: 10 * time.Minute, Count: 2}}, false},
+		{"at threshold", []VelocityCount{{Key: "prefix:192.0.2.0/24", Window: 10 * time.Minute, Count: 3}}, true},
+		{"above threshold", []VelocityCount{{Key: "prefix:192.0.2.0/24", Window: 10 * time.Minute, Count: 4}}, true},
+		{"another window", []VelocityCount{{Key: "prefix:192.0.2.0/24", Window: time.Hour, Count: 4}}, false},
+		// Not counted, e.g. for an address in the IP history
+		{"not counted", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := newTestInput("1", "192.0.2.1")
+			in.Velocity = tt.velocity
+			result, err := r.Evaluate(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (result != nil && result.Action == ActionAct) != tt.want {
+				t.Errorf("Evaluate() = %+v, want act %v", result, tt.want)
+			}
+		})
+	}
+}
@@ -90,8 +90,8 @@ func (config *Config) SendWorkerMessage(event *structs.AccoutCreatedEvent) error
 			Str("process", "queues::SendWorkerMessage::json.Marshal()").
 			Str("mastodon.userId", event.Object.Id).
 			Str("mastodon.username", event.Object.Username).
-			Str("mastodon.domian", event.Object.Domain).
-			Str("mastodon.createdAt", event.Object.CreatedAt).
+			Str("mastodon.domian", event.Object.DomainName()).
+			Time("mastodon.createdAt", event.Object.CreatedAt.Time).
 			Err(err).
 			Msg("error marshalling event to JSON")
 		return err
@@ -107,8 +107,8 @@ func (config *Config) SendWorkerMessage(event *structs.AccoutCreatedEvent) error
 			Str("process", "queues::SendWorkerMessage::sqs.SendMessage()").
 			Str("mastodon.userId", event.Object.Id).
 			Str("mastodon.username", event.Object.Username).
-			Str("mastodon.domian", event.Object.Domain).
-			Str("mastodon.createdAt", event.Object.CreatedAt).
+			Str("mastodon.domian", event.Object.DomainName()).
+			Time("mastodon.createdAt", event.Object.CreatedAt.Time).
 			Err(err).
 			Msg("error sending message to SQS")
 		return err
@@ -118,8 +118,8 @@ func (config *Config) SendWorkerMessage(event *structs.AccoutCreatedEvent) error
 		Str("sqs.messageId", *opt.MessageId).
 		Str("mastodon.userId", event.Object.Id).
 		Str("mastodon.username", event.Object.Username).
-		Str("mastodon.domian", event.Object.Domain).
-		Str("mastodon.createdAt", event.Object.CreatedAt).
+		Str("mastodon.domian", event.Object.DomainName()).
+		Time("mastodon.createdAt", event.Object.CreatedAt.Time).
 		Msg("sent message to SQS")
 	return err
 }
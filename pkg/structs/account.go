@@ -0,0 +1,177 @@
+package structs
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AdminAccount is the Mastodon Admin::Account entity. It is sent as the
+// object of the "account.created" webhook and returned by the admin
+// accounts API, see https://docs.joinmastodon.org/entities/Admin_Account/
+// An example webhook payload is in examples/account.created.json.
+type AdminAccount struct {
+	Id        string  `json:"id"`
+	Username  string  `json:"username"`
+	Domain    *string `json:"domain"`
+	CreatedAt Time    `json:"created_at"`
+	Email     string  `json:"email"`
+
+	// Ip is the most recent IP address used by the account, if known.
+	Ip  string    `json:"ip"`
+	Ips []AdminIP `json:"ips"`
+
+	Locale        string `json:"locale"`
+	InviteRequest string `json:"invite_request"`
+	Role          Role   `json:"role"`
+
+	Confirmed  bool `json:"confirmed"`
+	Approved   bool `json:"approved"`
+	Disabled   bool `json:"disabled"`
+	Silenced   bool `json:"silenced"`
+	Suspended  bool `json:"suspended"`
+	Sensitized bool `json:"sensitized"`
+
+	Account                Account `json:"account"`
+	CreatedByApplicationID *string `json:"created_by_application_id,omitempty"`
+	InvitedByAccountID     *string `json:"invited_by_account_id,omitempty"`
+}
+
+// DomainName returns the account's domain, or an empty string for local accounts.
+func (a *AdminAccount) DomainName() string {
+	if a.Domain == nil {
+		return ""
+	}
+	return *a.Domain
+}
+
+// AdminIP is an IP address the account has used, and when.
+type AdminIP struct {
+	Ip     string `json:"ip"`
+	UsedAt Time   `json:"used_at"`
+}
+
+// EventObject is the account sent with the "account.created" webhook.
+type EventObject = AdminAccount
+
+// EventIP is an IP address in the webhook account's IP history.
+type EventIP = AdminIP
+
+// Role is the account's role on the instance. Mastodon 4.0 sends
+// the id and permissions as numbers, later versions as strings,
+// and versions before 4.0 send only the role name.
+type Role struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Position    int    `json:"position"`
+	Permissions uint64 `json:"permissions"`
+	Highlighted bool   `json:"highlighted"`
+	CreatedAt   Time   `json:"created_at"`
+	UpdatedAt   Time   `json:"updated_at"`
+}
+
+// UnmarshalJSON accepts each of the role formats sent by Mastodon.
+func (r *Role) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*r = Role{Name: name}
+		return nil
+	}
+
+	type role Role
+	aux := struct {
+		*role
+		ID          json.RawMessage `json:"id"`
+		Permissions json.RawMessage `json:"permissions"`
+	}{role: (*role)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	r.ID = rawString(aux.ID)
+	if permissions := rawString(aux.Permissions); permissions != "" {
+		p, err := strconv.ParseUint(permissions, 10, 64)
+		if err != nil {
+			return err
+		}
+		r.Permissions = p
+	}
+	return nil
+}
+
+// rawString returns a JSON string or number as a string.
+func rawString(data json.RawMessage) string {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return ""
+	}
+	return strings.Trim(s, `"`)
+}
+
+// Time is a timestamp sent by Mastodon. Unlike time.Time, an empty
+// or null value unmarshals to the zero time instead of an error.
+type Time struct {
+	time.Time
+}
+
+// UnmarshalJSON accepts an RFC 3339 timestamp, an empty string or null.
+func (t *Time) UnmarshalJSON(data []byte) error {
+	switch strings.TrimSpace(string(data)) {
+	case "null", `""`:
+		t.Time = time.Time{}
+		return nil
+	}
+	return t.Time.UnmarshalJSON(data)
+}
+
+// Account is the public Mastodon Account entity for an Admin::Account,
+// see https://docs.joinmastodon.org/entities/Account/
+type Account struct {
+	ID             string `json:"id"`
+	Username       string `json:"username"`
+	Acct           string `json:"acct"`
+	DisplayName    string `json:"display_name"`
+	Locked         bool   `json:"locked"`
+	Bot            bool   `json:"bot"`
+	Discoverable   *bool  `json:"discoverable"`
+	Group          bool   `json:"group"`
+	CreatedAt      Time   `json:"created_at"`
+	Note           string `json:"note"`
+	URL            string `json:"url"`
+	Avatar         string `json:"avatar"`
+	AvatarStatic   string `json:"avatar_static"`
+	Header         string `json:"header"`
+	HeaderStatic   string `json:"header_static"`
+	FollowersCount int    `json:"followers_count"`
+	FollowingCount int    `json:"following_count"`
+	StatusesCount  int    `json:"statuses_count"`
+
+	// LastStatusAt is the date (YYYY-MM-DD) of the account's last status, if any.
+	LastStatusAt *string `json:"last_status_at"`
+
+	Noindex *bool         `json:"noindex"`
+	Emojis  []CustomEmoji `json:"emojis"`
+	Fields  []Field       `json:"fields"`
+}
+
+// CustomEmoji is a custom emoji used in an account's name or profile.
+type CustomEmoji struct {
+	Shortcode       string `json:"shortcode"`
+	URL             string `json:"url"`
+	StaticURL       string `json:"static_url"`
+	VisibleInPicker bool   `json:"visible_in_picker"`
+	Category        string `json:"category,omitempty"`
+}
+
+// Field is a profile metadata field.
+type Field struct {
+	Name       string `json:"name"`
+	Value      string `json:"value"`
+	VerifiedAt *Time  `json:"verified_at"`
+}
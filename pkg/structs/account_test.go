@@ -0,0 +1,176 @@
+package structs
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAccountCreatedEventExample(t *testing.T) {
+	data, err := os.ReadFile("../../examples/account.created.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event AccoutCreatedEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("Unmarshal(): %v", err)
+	}
+
+	want := time.Date(2023, 1, 4, 1, 57, 3, 708000000, time.UTC)
+	if !event.CreatedAt.Equal(want) {
+		t.Errorf("CreatedAt = %s, want %s", event.CreatedAt, want)
+	}
+
+	account := event.Object
+	if account.Id != "109628451946059725" || account.Username != "test001" {
+		t.Errorf("account = %s %s, want 109628451946059725 test001", account.Id, account.Username)
+	}
+	if account.Domain != nil || account.DomainName() != "" {
+		t.Errorf("Domain = %v, want nil for a local account", account.Domain)
+	}
+	if account.Role.ID != "-99" || account.Role.Permissions != 65536 {
+		t.Errorf("Role = %+v, want id -99 and permissions 65536", account.Role)
+	}
+	if len(account.Ips) != 1 || account.Ips[0].Ip != "73.207.229.36" {
+		t.Fatalf("Ips = %+v, want 73.207.229.36", account.Ips)
+	}
+	usedAt := time.Date(2023, 1, 4, 1, 57, 3, 714000000, time.UTC)
+	if !account.Ips[0].UsedAt.Equal(usedAt) {
+		t.Errorf("Ips[0].UsedAt = %s, want %s", account.Ips[0].UsedAt, usedAt)
+	}
+	if account.Account.Acct != "test001" {
+		t.Errorf("Account.Acct = %q, want test001", account.Account.Acct)
+	}
+	if account.Account.Note != "<p>Testing new signups</p>" {
+		t.Errorf("Account.Note = %q, want <p>Testing new signups</p>", account.Account.Note)
+	}
+	accountCreatedAt := time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC)
+	if !account.Account.CreatedAt.Equal(accountCreatedAt) {
+		t.Errorf("Account.CreatedAt = %s, want %s", account.Account.CreatedAt, accountCreatedAt)
+	}
+}
+
+func TestAdminAccountUnmarshal(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   string
+		role      Role
+		domain    string
+		createdAt time.Time
+		ips       []string
+	}{
+		{
+			name: "mastodon 4.0 numeric role",
+			payload: `{"id": "1", "username": "alice", "domain": null, "created_at": "2023-01-04T01:57:03.566Z",
+				"role": {"id": -99, "name": "", "color": "", "position": -1, "permissions": 65536, "highlighted": false,
+				"created_at": "2022-11-18T22:16:44.580Z", "updated_at": "2022-11-18T22:16:44.580Z"}}`,
+			role:      Role{ID: "-99", Position: -1, Permissions: 65536},
+			createdAt: time.Date(2023, 1, 4, 1, 57, 3, 566000000, time.UTC),
+		},
+		{
+			name: "later string role",
+			payload: `{"id": "1", "username": "alice", "domain": null, "created_at": "2023-01-04T01:57:03.566Z",
+				"role": {"id": "3", "name": "Owner", "color": "#ff3838", "permissions": "1", "highlighted": true}}`,
+			role:      Role{ID: "3", Name: "Owner", Color: "#ff3838", Permissions: 1, Highlighted: true},
+			createdAt: time.Date(2023, 1, 4, 1, 57, 3, 566000000, time.UTC),
+		},
+		{
+			name:      "pre 4.0 name only role",
+			payload:   `{"id": "1", "username": "alice", "domain": null, "created_at": "2023-01-04T01:57:03.566Z", "role": "admin"}`,
+			role:      Role{Name: "admin"},
+			createdAt: time.Date(2023, 1, 4, 1, 57, 3, 566000000, time.UTC),
+		},
+		{
+			name:    "null role",
+			payload: `{"id": "1", "username": "alice", "domain": null, "role": null}`,
+		},
+		{
+			name:    "remote account",
+			payload: `{"id": "1", "username": "alice", "domain": "example.com"}`,
+			domain:  "example.com",
+		},
+		{
+			name: "ip history",
+			payload: `{"id": "1", "username": "alice", "domain": null, "ip": "192.0.2.2",
+				"ips": [{"ip": "192.0.2.1", "used_at": "2023-01-03T10:00:00.000Z"}, {"ip": "192.0.2.2", "used_at": ""}]}`,
+			ips: []string{"192.0.2.1", "192.0.2.2"},
+		},
+		{
+			name: "empty created_at",
+			payload: `{"id": "1", "username": "alice", "domain": null, "created_at": "",
+				"account": {"created_at": "", "fields": [{"name": "web", "value": "example.com", "verified_at": ""}]}}`,
+		},
+		{
+			name:    "null created_at",
+			payload: `{"id": "1", "username": "alice", "domain": null, "created_at": null}`,
+		},
+		{
+			name:    "missing created_at",
+			payload: `{"id": "1", "username": "alice", "domain": null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var account AdminAccount
+			if err := json.Unmarshal([]byte(tt.payload), &account); err != nil {
+				t.Fatalf("Unmarshal(): %v", err)
+			}
+
+			if account.Role.ID != tt.role.ID || account.Role.Name != tt.role.Name || account.Role.Color != tt.role.Color ||
+				account.Role.Position != tt.role.Position || account.Role.Permissions != tt.role.Permissions ||
+				account.Role.Highlighted != tt.role.Highlighted {
+				t.Errorf("Role = %+v, want %+v", account.Role, tt.role)
+			}
+			if got := account.DomainName(); got != tt.domain {
+				t.Errorf("DomainName() = %q, want %q", got, tt.domain)
+			}
+			if !account.CreatedAt.Equal(tt.createdAt) {
+				t.Errorf("CreatedAt = %s, want %s", account.CreatedAt, tt.createdAt)
+			}
+			if len(account.Ips) != len(tt.ips) {
+				t.Fatalf("Ips = %+v, want %v", account.Ips, tt.ips)
+			}
+			for i, ip := range tt.ips {
+				if account.Ips[i].Ip != ip {
+					t.Errorf("Ips[%d] = %s, want %s", i, account.Ips[i].Ip, ip)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeUnmarshal(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: `"2023-01-04T01:57:03.566Z"`, want: time.Date(2023, 1, 4, 1, 57, 3, 566000000, time.UTC)},
+		{in: `""`},
+		{in: `null`},
+		{in: `"yesterday"`, wantErr: true},
+		{in: `12`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var got Time
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal(%s) = %s, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%s): %v", tt.in, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Unmarshal(%s) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,160 @@
 package structs
 
+import "time"
+
 // Output is marshalled to JSON and sent back to the
 // API GW at the end of Lambda function execution.
 type Output struct {
-	Error  *Err           `json:"error"`
-	Status string         `json:"status"`
-	Users  *[]EventObject `json:"user"`
+	Error       *Err               `json:"error"`
+	Status      string             `json:"status"`
+	Users       *[]User            `json:"user"`
+	WouldAct    *[]WouldAct        `json:"would_act,omitempty"`
+	Decisions   *[]Decision        `json:"decisions,omitempty"`
+	Divergences *[]Divergence      `json:"divergences,omitempty"`
+	FollowUps   *[]FollowUpOutcome `json:"follow_ups,omitempty"`
+}
+
+// Decision records how an account was evaluated against the policy and
+// why it was, or was not, acted on.
+type Decision struct {
+	Account       EventObject   `json:"account"`
+	Enrichment    Enrichment    `json:"enrichment"`
+	Rules         []RuleOutcome `json:"rules"`
+	Action        string        `json:"action"`
+	Rule          string        `json:"rule,omitempty"`
+	Reason        string        `json:"reason,omitempty"`
+	Match         string        `json:"match,omitempty"`
+	Level         string        `json:"level,omitempty"`
+	Score         int           `json:"score"`
+	IPs           []IPOutcome   `json:"ips,omitempty"`
+	DecidingIP    *IPOutcome    `json:"deciding_ip,omitempty"`
+	WouldAct      *RuleOutcome  `json:"would_act,omitempty"`
+	IPBlock       *IPBlock      `json:"ip_block,omitempty"`
+	EmailBlock    *EmailBlock   `json:"email_block,omitempty"`
+	FollowUp      *FollowUp     `json:"follow_up,omitempty"`
+	Enforced      bool          `json:"enforced"`
+	Error         string        `json:"error,omitempty"`
+	Policy        string        `json:"policy"`
+	PolicyVersion string        `json:"policy_version"`
+}
+
+// Divergence is an account on which the shadow
+// policy disagreed with the active policy.
+type Divergence struct {
+	Active Decision `json:"active"`
+	Shadow Decision `json:"shadow"`
+}
+
+// Enrichment is the data looked up for an account's IP address.
+type Enrichment struct {
+	IP             string          `json:"ip"`
+	IPClass        string          `json:"ip_class,omitempty"`
+	Country        string          `json:"country,omitempty"`
+	Continent      string          `json:"continent,omitempty"`
+	ASN            uint            `json:"asn,omitempty"`
+	ASOrganization string          `json:"as_organization,omitempty"`
+	Velocity       []VelocityCount `json:"velocity,omitempty"`
+}
+
+// VelocityCount is the number of recent signups counted against a key,
+// e.g. "prefix:192.0.2.0/24", within a window.
+type VelocityCount struct {
+	Key    string `json:"key"`
+	Window string `json:"window"`
+	Count  int    `json:"count"`
+}
+
+// IPOutcome is the enrichment, and the decision if it was
+// evaluated, for an IP address in the account's history.
+type IPOutcome struct {
+	Enrichment
+	UsedAt *time.Time `json:"used_at,omitempty"`
+	Action string     `json:"action,omitempty"`
+	Rule   string     `json:"rule,omitempty"`
+	Reason string     `json:"reason,omitempty"`
+	Error  string     `json:"error,omitempty"`
+
+	// Rules is the rule trace for the address, only set for the deciding IP.
+	Rules []RuleOutcome `json:"rules,omitempty"`
+}
+
+// IPBlock is the Mastodon IP block created, or found to already
+// exist, for the network of an account that was acted on. ID is only
+// set for a block mastoban created, so revert can delete it.
+type IPBlock struct {
+	ID        string `json:"id,omitempty"`
+	IP        string `json:"ip"`
+	Severity  string `json:"severity"`
+	ExpiresIn string `json:"expires_in,omitempty"`
+	Created   bool   `json:"created"`
+	Existing  string `json:"existing,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// EmailBlock is the Mastodon email domain and canonical email blocks
+// created, or found to already exist, for an account that was acted on.
+// Domain is only set once the email domain has passed the threshold.
+// The IDs are only set for blocks mastoban created, so revert can
+// delete them.
+type EmailBlock struct {
+	Domain           string `json:"domain,omitempty"`
+	Count            int    `json:"count,omitempty"`
+	Canonical        bool   `json:"canonical,omitempty"`
+	DryRun           bool   `json:"dry_run,omitempty"`
+	DomainCreated    bool   `json:"domain_created"`
+	DomainID         string `json:"domain_id,omitempty"`
+	CanonicalCreated bool   `json:"canonical_created"`
+	CanonicalID      string `json:"canonical_id,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// FollowUp is the follow-up action scheduled for an account that was
+// acted on: an escalation to Level, or lifting the level, after a delay.
+type FollowUp struct {
+	After     string     `json:"after"`
+	Level     string     `json:"level,omitempty"`
+	Lift      bool       `json:"lift,omitempty"`
+	DueAt     *time.Time `json:"due_at,omitempty"`
+	Scheduled bool       `json:"scheduled"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// FollowUpOutcome is the outcome of running a follow-up action:
+// "escalated", "lifted", "cleared" (a moderator changed the account),
+// "gone" (the account was deleted) or "failed" (retried on the next run).
+type FollowUpOutcome struct {
+	ID            string    `json:"id"`
+	AccountID     string    `json:"account_id"`
+	Username      string    `json:"username"`
+	Rule          string    `json:"rule"`
+	Level         string    `json:"level"`
+	FollowUpLevel string    `json:"follow_up_level,omitempty"`
+	Lift          bool      `json:"lift,omitempty"`
+	DueAt         time.Time `json:"due_at"`
+	Outcome       string    `json:"outcome"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// RuleOutcome is the outcome of a single rule checked during evaluation.
+// Outcome is the rule's action, "no_opinion", "inactive" or "error".
+type RuleOutcome struct {
+	Rule    string `json:"rule"`
+	Outcome string `json:"outcome"`
+	Reason  string `json:"reason,omitempty"`
+	Match   string `json:"match,omitempty"`
+	Level   string `json:"level,omitempty"`
+	Weight  int    `json:"weight,omitempty"`
+	DryRun  bool   `json:"dry_run,omitempty"`
+}
+
+// WouldAct is an account the policy would have acted on if
+// the rule (or the whole policy) was not in dry run mode.
+type WouldAct struct {
+	User   EventObject `json:"user"`
+	Action string      `json:"action"`
+	Rule   string      `json:"rule"`
+	Level  string      `json:"level"`
+	Reason string      `json:"reason"`
 }
 
 // Err is a custom error message stuct marshalled
@@ -20,78 +169,15 @@ type Err struct {
 // to the "account.create" event.
 type AccoutCreatedEvent struct {
 	Event     string      `json:"event"`
-	CreatedAt string      `json:"created_at"`
+	CreatedAt Time        `json:"created_at"`
 	Object    EventObject `json:"object"`
 }
 
-// EventObject contains the Mastodon account details
-// required to assess, and if needed, suspend the account.
-type EventObject struct {
-	Id        string `json:"id"`
-	Username  string `json:"username"`
-	Domain    string `json:"domain"`
-	CreatedAt string `json:"created_at"`
-	Email     string `json:"email"`
-	Ip        string `json:"ip"`
-}
-
-/* Example "account.created" event sent by a Mastondon account.created webhook.
-{
-	"event": "account.created",
-	"created_at": "2023-01-04T01:57:03.708Z",
-	"object": {
-		"id": "109628451946059725",
-		"username": "test001",
-		"domain": null,
-		"created_at": "2023-01-04T01:57:03.566Z",
-		"email": "test001@sigler.io",
-		"ip": "73.207.229.36",
-		"role": {
-			"id": -99,
-			"name": "",
-			"color": "",
-			"position": -1,
-			"permissions": 65536,
-			"highlighted": false,
-			"created_at": "2022-11-18T22:16:44.580Z",
-			"updated_at": "2022-11-18T22:16:44.580Z"
-		},
-		"confirmed": false,
-		"suspended": false,
-		"silenced": false,
-		"sensitized": false,
-		"disabled": false,
-		"approved": false,
-		"locale": "en",
-		"invite_request": "I need to test",
-		"ips": [{
-			"ip": "73.207.229.36",
-			"used_at": "2023-01-04T01:57:03.714Z"
-		}],
-		"account": {
-			"id": "109628451946059725",
-			"username": "test001",
-			"acct": "test001",
-			"display_name": "Test001",
-			"locked": false,
-			"bot": false,
-			"discoverable": null,
-			"group": false,
-			"created_at": "2023-01-04T00:00:00.000Z",
-			"note": "",
-			"url": "https://nifty-moose.com/@test001",
-			"avatar": "https://nifty-moose.com/avatars/original/missing.png",
-			"avatar_static": "https://nifty-moose.com/avatars/original/missing.png",
-			"header": "https://nifty-moose.com/headers/original/missing.png",
-			"header_static": "https://nifty-moose.com/headers/original/missing.png",
-			"followers_count": 0,
-			"following_count": 0,
-			"statuses_count": 0,
-			"last_status_at": null,
-			"noindex": false,
-			"emojis": [],
-			"fields": []
-		}
-	}
+// User is an account acted on, as reported in the Output.
+type User struct {
+	Username  string    `json:"username"`
+	Id        string    `json:"id"`
+	Domain    *string   `json:"domain"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
 }
-*/
@@ -0,0 +1,174 @@
+package velocity
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/rs/xid"
+	"github.com/rs/zerolog"
+)
+
+// eventTimeFormat is a fixed width UTC timestamp, so events sort by time.
+const eventTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// DynamoDBOption for the DynamoDB store
+type DynamoDBOption func(s *DynamoDBStore)
+
+// DynamoDBStore keeps events in a DynamoDB table, so counts are shared by
+// every Lambda instance. The table needs a string partition key named "key",
+// a string sort key named "event", and TTL enabled on the "expires_at" attribute.
+type DynamoDBStore struct {
+	table   string
+	region  string
+	profile string
+	log     *zerolog.Logger
+	db      *dynamodb.Client
+}
+
+// NewDynamoDBStore creates a new DynamoDBStore.
+func NewDynamoDBStore(opts ...DynamoDBOption) (*DynamoDBStore, error) {
+	s := &DynamoDBStore{}
+
+	// apply the list of options to DynamoDBStore
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.table == "" {
+		return nil, &NoTable{}
+	}
+
+	if s.region == "" {
+		s.region = os.Getenv("AWS_REGION")
+	}
+
+	awsConfig, err := config.LoadDefaultConfig(context.TODO(), func(o *config.LoadOptions) error {
+		o.Region = s.region
+		if s.profile != "" {
+			o.SharedConfigProfile = s.profile
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// set up logger if not provided
+	if s.log == nil {
+		log := zerolog.New(os.Stderr).With().Timestamp().Logger()
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		s.log = &log
+	}
+
+	s.db = dynamodb.NewFromConfig(awsConfig)
+	return s, nil
+}
+
+// WithLogger sets the logger to use
+func WithLogger(log *zerolog.Logger) DynamoDBOption {
+	return func(s *DynamoDBStore) {
+		s.log = log
+	}
+}
+
+// WithProfile sets the AWS profile to use
+func WithProfile(profile string) DynamoDBOption {
+	return func(s *DynamoDBStore) {
+		s.profile = profile
+	}
+}
+
+// WithRegion sets the AWS region to use
+func WithRegion(region string) DynamoDBOption {
+	return func(s *DynamoDBStore) {
+		s.region = region
+	}
+}
+
+// WithTable sets the DynamoDB table name
+func WithTable(table string) DynamoDBOption {
+	return func(s *DynamoDBStore) {
+		s.table = table
+	}
+}
+
+// Record adds an event for the key. DynamoDB removes the event once ttl has passed.
+func (s *DynamoDBStore) Record(key string, id string, at time.Time, ttl time.Duration) error {
+	_, err := s.db.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      eventItem(key, id, at, ttl),
+	})
+	if err != nil {
+		s.log.Error().
+			Str("process", "velocity::Record::dynamodb.PutItem()").
+			Str("table", s.table).
+			Str("key", key).
+			Err(err).
+			Msg("error recording velocity event")
+		return err
+	}
+	return nil
+}
+
+// Count returns the number of events recorded for the key at or after since.
+func (s *DynamoDBStore) Count(key string, since time.Time) (int, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		KeyConditionExpression: aws.String("#key = :key AND #event >= :since"),
+		ExpressionAttributeNames: map[string]string{
+			"#key":   "key",
+			"#event": "event",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":key":   &types.AttributeValueMemberS{Value: key},
+			":since": &types.AttributeValueMemberS{Value: sinceKey(since)},
+		},
+		Select: types.SelectCount,
+	}
+
+	count := 0
+	for {
+		out, err := s.db.Query(context.TODO(), input)
+		if err != nil {
+			s.log.Error().
+				Str("process", "velocity::Count::dynamodb.Query()").
+				Str("table", s.table).
+				Str("key", key).
+				Err(err).
+				Msg("error counting velocity events")
+			return 0, err
+		}
+		count += int(out.Count)
+		if len(out.LastEvaluatedKey) == 0 {
+			return count, nil
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// eventItem returns the item for an event. The sort key starts with the
+// event time, so Count can query a time range, followed by the id, so
+// recording the same event twice overwrites it. Events without an id
+// get a unique one. expires_at is the TTL attribute, in Unix seconds.
+func eventItem(key string, id string, at time.Time, ttl time.Duration) map[string]types.AttributeValue {
+	if id == "" {
+		id = xid.New().String()
+	}
+	return map[string]types.AttributeValue{
+		"key":        &types.AttributeValueMemberS{Value: key},
+		"event":      &types.AttributeValueMemberS{Value: at.UTC().Format(eventTimeFormat) + "#" + id},
+		"expires_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(at.Add(ttl).Unix(), 10)},
+	}
+}
+
+// sinceKey returns the lowest sort key of the events at or after since.
+func sinceKey(since time.Time) string {
+	return since.UTC().Format(eventTimeFormat)
+}
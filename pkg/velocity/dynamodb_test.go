@@ -0,0 +1,118 @@
+package velocity
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// itemString returns a string attribute of an item.
+func itemString(t *testing.T, item map[string]types.AttributeValue, name string) string {
+	t.Helper()
+	value, ok := item[name].(*types.AttributeValueMemberS)
+	if !ok {
+		t.Fatalf("%s is %T, want a string", name, item[name])
+	}
+	return value.Value
+}
+
+func TestEventItem(t *testing.T) {
+	at := time.Date(2024, 3, 9, 15, 4, 5, 123000000, time.UTC)
+	item := eventItem("prefix:192.0.2.0/24", "109371562937486", at, 10*time.Minute)
+
+	if got := itemString(t, item, "key"); got != "prefix:192.0.2.0/24" {
+		t.Errorf("key = %q", got)
+	}
+	if got, want := itemString(t, item, "event"), "2024-03-09T15:04:05.123000000Z#109371562937486"; got != want {
+		t.Errorf("event = %q, want %q", got, want)
+	}
+
+	expires, ok := item["expires_at"].(*types.AttributeValueMemberN)
+	if !ok {
+		t.Fatalf("expires_at is %T, want a number", item["expires_at"])
+	}
+	if want := strconv.FormatInt(at.Add(10*time.Minute).Unix(), 10); expires.Value != want {
+		t.Errorf("expires_at = %s, want %s", expires.Value, want)
+	}
+}
+
+func TestEventItemUTC(t *testing.T) {
+	zone := time.FixedZone("UTC-5", -5*60*60)
+	at := time.Date(2024, 3, 9, 10, 4, 5, 0, zone)
+
+	got := itemString(t, eventItem("ip:192.0.2.1", "1", at, time.Hour), "event")
+	if want := "2024-03-09T15:04:05.000000000Z#1"; got != want {
+		t.Errorf("event = %q, want %q", got, want)
+	}
+}
+
+func TestEventItemSameEvent(t *testing.T) {
+	at := time.Now()
+	a := itemString(t, eventItem("ip:192.0.2.1", "1", at, time.Hour), "event")
+	b := itemString(t, eventItem("ip:192.0.2.1", "1", at, time.Hour), "event")
+	if a != b {
+		t.Errorf("the same event has different sort keys %q and %q", a, b)
+	}
+
+	// Events without an id are unique
+	a = itemString(t, eventItem("ip:192.0.2.1", "", at, time.Hour), "event")
+	b = itemString(t, eventItem("ip:192.0.2.1", "", at, time.Hour), "event")
+	if a == b {
+		t.Errorf("events without an id share the sort key %q", a)
+	}
+}
+
+func TestEventSortKeyOrder(t *testing.T) {
+	base := time.Date(2024, 3, 9, 15, 4, 5, 0, time.UTC)
+	times := []time.Time{
+		base,
+		base.Add(time.Nanosecond),
+		base.Add(time.Millisecond),
+		base.Add(9 * time.Second),
+		base.Add(10 * time.Second),
+		base.Add(time.Hour),
+		base.AddDate(0, 0, 1),
+	}
+
+	keys := []string{}
+	for i, at := range times {
+		keys = append(keys, itemString(t, eventItem("k", strconv.Itoa(len(times)-i), at, time.Hour), "event"))
+	}
+	if !sort.StringsAreSorted(keys) {
+		t.Errorf("sort keys are not in time order: %v", keys)
+	}
+	for _, key := range keys {
+		if len(strings.Split(key, "#")[0]) != len(eventTimeFormat) {
+			t.Errorf("sort key %q is not fixed width", key)
+		}
+	}
+}
+
+func TestSinceKey(t *testing.T) {
+	at := time.Date(2024, 3, 9, 15, 4, 5, 0, time.UTC)
+	event := itemString(t, eventItem("k", "1", at, time.Hour), "event")
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  bool // whether the event is counted
+	}{
+		{"before", at.Add(-time.Minute), true},
+		{"same instant", at, true},
+		{"same instant in another zone", at.In(time.FixedZone("UTC+9", 9*60*60)), true},
+		{"a nanosecond after", at.Add(time.Nanosecond), false},
+		{"after", at.Add(time.Minute), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := event >= sinceKey(tt.since); got != tt.want {
+				t.Errorf("%q >= %q = %v, want %v", event, sinceKey(tt.since), got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package velocity
+
+// NoTable is returned when the DynamoDB store is created without a table name.
+type NoTable struct {
+	Err error
+	Msg string
+}
+
+// Error returns the error message
+func (e *NoTable) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = "no table name. use WithTable()"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
@@ -0,0 +1,63 @@
+package velocity
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps events in memory. Counts are lost when the process
+// exits, and on Lambda each warm instance keeps its own counts, so use a
+// persistent store where signups are spread across instances.
+type MemoryStore struct {
+	mu     sync.Mutex
+	events map[string][]memoryEvent
+}
+
+// memoryEvent is a recorded event and when it expires.
+type memoryEvent struct {
+	id      string
+	at      time.Time
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{events: make(map[string][]memoryEvent)}
+}
+
+// Record adds an event for the key, forgetting any expired events for the key.
+func (s *MemoryStore) Record(key string, id string, at time.Time, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	events := s.events[key][:0]
+	for _, event := range s.events[key] {
+		if !event.expires.After(now) {
+			continue
+		}
+		if id != "" && event.id == id && event.at.Equal(at) {
+			continue
+		}
+		events = append(events, event)
+	}
+	events = append(events, memoryEvent{id: id, at: at, expires: at.Add(ttl)})
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].at.Before(events[j].at)
+	})
+	s.events[key] = events
+	return nil
+}
+
+// Count returns the number of events recorded for the key at or after since.
+func (s *MemoryStore) Count(key string, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := s.events[key]
+	first := sort.Search(len(events), func(i int) bool {
+		return !events[i].at.Before(since)
+	})
+	return len(events) - first, nil
+}
@@ -0,0 +1,144 @@
+package velocity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreWindow(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	for i, ago := range []time.Duration{2 * time.Hour, 30 * time.Minute, time.Minute, 45 * time.Minute} {
+		if err := s.Record("ip:192.0.2.1", string(rune('a'+i)), now.Add(-ago), 3*time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  int
+	}{
+		{"all", now.Add(-3 * time.Hour), 4},
+		{"last hour", now.Add(-time.Hour), 3},
+		{"last 10 minutes", now.Add(-10 * time.Minute), 1},
+		{"at an event", now.Add(-30 * time.Minute), 2},
+		{"future", now.Add(time.Minute), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Count("ip:192.0.2.1", tt.since)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Count() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+
+	// Expired an hour ago
+	if err := s.Record("asn:AS14061", "a", now.Add(-2*time.Hour), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// Recording again forgets the expired event
+	if err := s.Record("asn:AS14061", "b", now, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Count("asn:AS14061", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 1 {
+		t.Errorf("Count() = %d, want 1", got)
+	}
+}
+
+func TestMemoryStoreSameEvent(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		events []struct {
+			id string
+			at time.Time
+		}
+		want int
+	}{
+		{
+			name: "same id and time counted once",
+			events: []struct {
+				id string
+				at time.Time
+			}{{"1", now}, {"1", now}, {"1", now}},
+			want: 1,
+		},
+		{
+			name: "same id at different times",
+			events: []struct {
+				id string
+				at time.Time
+			}{{"1", now}, {"1", now.Add(-time.Minute)}},
+			want: 2,
+		},
+		{
+			name: "different ids at the same time",
+			events: []struct {
+				id string
+				at time.Time
+			}{{"1", now}, {"2", now}},
+			want: 2,
+		},
+		{
+			name: "events without an id are never merged",
+			events: []struct {
+				id string
+				at time.Time
+			}{{"", now}, {"", now}},
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStore()
+			for _, event := range tt.events {
+				if err := s.Record("email_domain:example.com", event.id, event.at, time.Hour); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := s.Count("email_domain:example.com", now.Add(-time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Count() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreKeys(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	s.Record("ip:192.0.2.1", "1", now, time.Hour)
+	s.Record("ip:192.0.2.1", "2", now, time.Hour)
+	s.Record("ip:192.0.2.2", "3", now, time.Hour)
+
+	for key, want := range map[string]int{"ip:192.0.2.1": 2, "ip:192.0.2.2": 1, "ip:192.0.2.3": 0} {
+		got, err := s.Count(key, now.Add(-time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Count(%q) = %d, want %d", key, got, want)
+		}
+	}
+}
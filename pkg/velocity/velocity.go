@@ -0,0 +1,62 @@
+package velocity
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store keeps the events counted by sliding window velocity rules.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Record adds an event for the key at the given time. id identifies
+	// what is counted, e.g. the account ID: recording the same id at the
+	// same time again, such as for a redelivered webhook, replaces the
+	// event rather than counting it twice. The event may be forgotten
+	// once ttl has passed.
+	Record(key string, id string, at time.Time, ttl time.Duration) error
+
+	// Count returns the number of events recorded for the key at or after since.
+	Count(key string, since time.Time) (int, error)
+}
+
+// IPKey returns the counter key for an exact IP address.
+func IPKey(ip net.IP) string {
+	return "ip:" + ip.String()
+}
+
+// PrefixKey returns the counter key for the network containing the IP
+// address, using ipv4Prefix bits for IPv4 and ipv6Prefix bits for IPv6.
+func PrefixKey(ip net.IP, ipv4Prefix int, ipv6Prefix int) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		prefix := net.IPNet{IP: ip4.Mask(net.CIDRMask(ipv4Prefix, 32)), Mask: net.CIDRMask(ipv4Prefix, 32)}
+		return "prefix:" + prefix.String()
+	}
+	prefix := net.IPNet{IP: ip.Mask(net.CIDRMask(ipv6Prefix, 128)), Mask: net.CIDRMask(ipv6Prefix, 128)}
+	return "prefix:" + prefix.String()
+}
+
+// ASNKey returns the counter key for an autonomous system number.
+func ASNKey(asn uint) string {
+	return "asn:AS" + strconv.FormatUint(uint64(asn), 10)
+}
+
+// EmailDomainKey returns the counter key for an email domain.
+func EmailDomainKey(domain string) string {
+	return "email_domain:" + strings.ToLower(domain)
+}
+
+// SuspendedEmailDomainKey returns the counter key for accounts
+// with an email domain that were acted on.
+func SuspendedEmailDomainKey(domain string) string {
+	return "suspended_email_domain:" + strings.ToLower(domain)
+}
+
+// Subject returns the part of the key after the counter type, e.g. "192.0.2.0/24".
+func Subject(key string) string {
+	if i := strings.IndexByte(key, ':'); i >= 0 {
+		return key[i+1:]
+	}
+	return key
+}
@@ -0,0 +1,77 @@
+package velocity
+
+import (
+	"net"
+	"testing"
+)
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"ipv4", IPKey(net.ParseIP("192.0.2.1")), "ip:192.0.2.1"},
+		{"ipv6", IPKey(net.ParseIP("2001:db8::1")), "ip:2001:db8::1"},
+		{"ipv4-mapped ipv6", IPKey(net.ParseIP("::ffff:192.0.2.1")), "ip:192.0.2.1"},
+		{"ipv4 /24", PrefixKey(net.ParseIP("192.0.2.77"), 24, 64), "prefix:192.0.2.0/24"},
+		{"ipv4 /16", PrefixKey(net.ParseIP("192.0.2.77"), 16, 64), "prefix:192.0.0.0/16"},
+		{"ipv4 /32", PrefixKey(net.ParseIP("192.0.2.77"), 32, 64), "prefix:192.0.2.77/32"},
+		{"ipv4 ignores ipv6 prefix", PrefixKey(net.ParseIP("192.0.2.77"), 24, 48), "prefix:192.0.2.0/24"},
+		{"ipv4-mapped ipv6 uses ipv4 prefix", PrefixKey(net.ParseIP("::ffff:192.0.2.77"), 24, 64), "prefix:192.0.2.0/24"},
+		{"ipv6 /64", PrefixKey(net.ParseIP("2001:db8:1:2:3:4:5:6"), 24, 64), "prefix:2001:db8:1:2::/64"},
+		{"ipv6 /48", PrefixKey(net.ParseIP("2001:db8:1:2:3:4:5:6"), 24, 48), "prefix:2001:db8:1::/48"},
+		{"ipv6 /128", PrefixKey(net.ParseIP("2001:db8::6"), 24, 128), "prefix:2001:db8::6/128"},
+		{"asn", ASNKey(14061), "asn:AS14061"},
+		{"email domain is lower cased", EmailDomainKey("Example.COM"), "email_domain:example.com"},
+		{"suspended email domain", SuspendedEmailDomainKey("Example.com"), "suspended_email_domain:example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixKeySharedByNetwork(t *testing.T) {
+	a := PrefixKey(net.ParseIP("198.51.100.1"), 24, 64)
+	b := PrefixKey(net.ParseIP("198.51.100.254"), 24, 64)
+	c := PrefixKey(net.ParseIP("198.51.101.1"), 24, 64)
+	if a != b {
+		t.Errorf("addresses in the same /24 have different keys: %q, %q", a, b)
+	}
+	if a == c {
+		t.Errorf("addresses in different /24s share the key %q", a)
+	}
+
+	a = PrefixKey(net.ParseIP("2001:db8:0:1::1"), 24, 64)
+	b = PrefixKey(net.ParseIP("2001:db8:0:1:ffff::1"), 24, 64)
+	c = PrefixKey(net.ParseIP("2001:db8:0:2::1"), 24, 64)
+	if a != b {
+		t.Errorf("addresses in the same /64 have different keys: %q, %q", a, b)
+	}
+	if a == c {
+		t.Errorf("addresses in different /64s share the key %q", a)
+	}
+}
+
+func TestSubject(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"prefix:192.0.2.0/24", "192.0.2.0/24"},
+		{"ip:2001:db8::1", "2001:db8::1"},
+		{"asn:AS14061", "AS14061"},
+		{"nocolon", "nocolon"},
+	}
+
+	for _, tt := range tests {
+		if got := Subject(tt.key); got != tt.want {
+			t.Errorf("Subject(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}